@@ -0,0 +1,74 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/zeze322/todo/db"
+	"github.com/zeze322/todo/lib"
+)
+
+// maxBackupSize limits the upload of a backup to restore.
+const maxBackupSize = 1 << 30
+
+// handleBackup streams a snapshot of the database, see db.Storage Backup.
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) error {
+	if !db.UserFrom(r.Context()).Admin {
+		return errForbidden("admin")
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="scheduler-%s.db"`, time.Now().Format(lib.Layout)))
+
+	if err := s.store.Backup(r.Context(), w); err != nil {
+		w.Header().Del("Content-Disposition")
+		return err
+	}
+
+	return nil
+}
+
+// handleRestore replaces the database with the backup sent in the "file"
+// field of a multipart form.
+func (s *Server) handleRestore(w http.ResponseWriter, r *http.Request) error {
+	if !db.UserFrom(r.Context()).Admin {
+		return errForbidden("admin")
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBackupSize+multipartOverhead)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return err
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return fmt.Errorf("file not specified")
+		} else if err != nil {
+			return err
+		}
+
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		defer part.Close()
+
+		var tooLarge *http.MaxBytesError
+
+		err = s.store.Restore(r.Context(), part)
+		if errors.As(err, &tooLarge) {
+			return lib.WriteJSON(w, http.StatusRequestEntityTooLarge, lib.ApiErr{Error: "backup is too large"})
+		} else if err != nil {
+			return err
+		}
+
+		return lib.WriteJSON(w, http.StatusOK, lib.EmptyJSON{})
+	}
+}
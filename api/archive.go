@@ -0,0 +1,63 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/zeze322/todo/db"
+	"github.com/zeze322/todo/lib"
+)
+
+// handleGetCompletedTasks lists the archive. from and to are the first
+// and the last day of completion, both optional.
+func (s *Server) handleGetCompletedTasks(w http.ResponseWriter, r *http.Request) error {
+	var from, to time.Time
+
+	if v := r.FormValue("from"); v != "" {
+		day, err := parseDay(v)
+		if err != nil {
+			return err
+		}
+		from = day
+	}
+
+	if v := r.FormValue("to"); v != "" {
+		day, err := parseDay(v)
+		if err != nil {
+			return err
+		}
+		to = day.AddDate(0, 0, 1)
+	}
+
+	tasks, err := s.store.GetCompletedTasks(r.Context(), from, to)
+	if err != nil {
+		return fmt.Errorf("failed to get completed tasks")
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, db.TasksResponse{Tasks: tasks})
+}
+
+func (s *Server) handleUncompleteTask(w http.ResponseWriter, r *http.Request) error {
+	id := r.FormValue("id")
+	if id == "" {
+		return fmt.Errorf("id not specified")
+	}
+
+	if err := s.store.UncompleteTask(r.Context(), id); err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, lib.EmptyJSON{})
+}
+
+// parseDay returns the local midnight of a date given in any format
+// accepted by parseDate.
+func parseDay(s string) (time.Time, error) {
+	date, err := parseDate(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.ParseInLocation(lib.Layout, date, time.Local)
+}
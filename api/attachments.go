@@ -0,0 +1,270 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/zeze322/todo/db"
+	"github.com/zeze322/todo/lib"
+)
+
+// multipartOverhead is allowed on top of the file size limit for the
+// rest of an upload request.
+const multipartOverhead = 1 << 20
+
+var errFileTooLarge = errors.New("file is too large")
+
+// AttachmentConfig sets where attached files are stored and how large a
+// single file may be.
+type AttachmentConfig struct {
+	Dir     string
+	MaxSize int64
+}
+
+func (s *Server) handleAttachments(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case "GET":
+		if r.FormValue("attachment") != "" {
+			return s.handleDownloadAttachment(w, r)
+		}
+		return s.handleGetAttachments(w, r)
+	case "POST":
+		return s.handleUploadAttachment(w, r)
+	case "DELETE":
+		return s.handleDeleteAttachment(w, r)
+	}
+
+	return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+func (s *Server) handleGetAttachments(w http.ResponseWriter, r *http.Request) error {
+	id := r.FormValue("id")
+	if id == "" {
+		return fmt.Errorf("id not specified")
+	}
+
+	attachments, err := s.store.GetAttachments(r.Context(), id)
+	if err != nil {
+		return fmt.Errorf("failed to get attachments")
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, db.AttachmentsResponse{Attachments: attachments})
+}
+
+// handleUploadAttachment stores the file sent in the "file" field of a
+// multipart form and responds with its metadata.
+func (s *Server) handleUploadAttachment(w http.ResponseWriter, r *http.Request) error {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		return fmt.Errorf("id not specified")
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.attachments.MaxSize+multipartOverhead)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return err
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return fmt.Errorf("file not specified")
+		} else if err != nil {
+			return err
+		}
+
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		defer part.Close()
+
+		a, err := s.saveAttachment(r.Context(), id, part.FileName(), part.Header.Get("Content-Type"), part)
+		if errors.Is(err, errFileTooLarge) {
+			return lib.WriteJSON(w, http.StatusRequestEntityTooLarge, lib.ApiErr{Error: err.Error()})
+		} else if err != nil {
+			return err
+		}
+
+		return lib.WriteJSON(w, http.StatusOK, a)
+	}
+}
+
+// saveAttachment writes the file to a temporary name while hashing it,
+// records the metadata and then moves the file to its final name, so a
+// failed upload never leaves a file behind.
+func (s *Server) saveAttachment(ctx context.Context, taskID, name, mimeType string, src io.Reader) (db.Attachment, error) {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "" || name == "." || name == "/" {
+		return db.Attachment{}, fmt.Errorf("file name should not be empty")
+	}
+
+	tmp, err := os.CreateTemp(s.attachments.Dir, "upload-*")
+	if err != nil {
+		return db.Attachment{}, fmt.Errorf("failed to store file")
+	}
+
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(src, s.attachments.MaxSize+1))
+	if err != nil {
+		return db.Attachment{}, fmt.Errorf("failed to store file")
+	}
+
+	if size > s.attachments.MaxSize {
+		return db.Attachment{}, fmt.Errorf("%w, the limit is %d bytes", errFileTooLarge, s.attachments.MaxSize)
+	}
+
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType = sniffType(tmp, name)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return db.Attachment{}, fmt.Errorf("failed to store file")
+	}
+
+	a := db.Attachment{
+		TaskID:   taskID,
+		Name:     name,
+		Size:     size,
+		MimeType: mimeType,
+		Checksum: hex.EncodeToString(hash.Sum(nil)),
+	}
+
+	if a.ID, err = s.store.CreateAttachment(ctx, a); err != nil {
+		return db.Attachment{}, err
+	}
+
+	if err := os.Rename(tmp.Name(), s.attachmentPath(a.ID)); err != nil {
+		s.store.DeleteAttachment(ctx, a.ID)
+		return db.Attachment{}, fmt.Errorf("failed to store file")
+	}
+
+	return s.store.GetAttachment(ctx, a.ID)
+}
+
+// sniffType guesses the MIME type from the file extension and then from
+// the first bytes of the file.
+func sniffType(f *os.File, name string) string {
+	if t := mime.TypeByExtension(filepath.Ext(name)); t != "" {
+		return t
+	}
+
+	buf := make([]byte, 512)
+
+	n, _ := f.ReadAt(buf, 0)
+
+	return http.DetectContentType(buf[:n])
+}
+
+// inertTypes are served as is, anything else could run as a page of the
+// app (HTML, SVG, scripts) and is sent as a plain download.
+var inertTypes = map[string]bool{
+	"application/pdf": true,
+	"application/zip": true,
+	"audio/mpeg":      true,
+	"image/gif":       true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+	"text/csv":        true,
+	"text/plain":      true,
+	"video/mp4":       true,
+}
+
+// downloadType is the Content-Type an attachment is served with.
+func downloadType(mimeType string) string {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil || !inertTypes[mediaType] {
+		return "application/octet-stream"
+	}
+
+	return mimeType
+}
+
+func (s *Server) handleDownloadAttachment(w http.ResponseWriter, r *http.Request) error {
+	a, err := s.store.GetAttachment(r.Context(), r.FormValue("attachment"))
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(s.attachmentPath(a.ID))
+	if err != nil {
+		return fmt.Errorf("attachment file is missing id: %s", a.ID)
+	}
+
+	defer f.Close()
+
+	w.Header().Set("Content-Type", downloadType(a.MimeType))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+	w.Header().Set("ETag", `"`+a.Checksum+`"`)
+
+	_, err = io.Copy(w, f)
+
+	return err
+}
+
+func (s *Server) handleDeleteAttachment(w http.ResponseWriter, r *http.Request) error {
+	id := r.FormValue("attachment")
+	if id == "" {
+		return fmt.Errorf("attachment not specified")
+	}
+
+	if err := s.store.DeleteAttachment(r.Context(), id); err != nil {
+		return err
+	}
+
+	if err := os.Remove(s.attachmentPath(id)); err != nil && !os.IsNotExist(err) {
+		log.Println("failed to remove attachment file", err)
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, lib.EmptyJSON{})
+}
+
+func (s *Server) attachmentPath(id string) string {
+	return filepath.Join(s.attachments.Dir, id)
+}
+
+// removeOrphanAttachments deletes files whose metadata is gone, which
+// happens when their task is purged from the trash.
+func (s *Server) removeOrphanAttachments(ctx context.Context) {
+	entries, err := os.ReadDir(s.attachments.Dir)
+	if err != nil {
+		log.Println("failed to read attachments dir", err)
+		return
+	}
+
+	ids, err := s.store.GetAttachmentIDs(ctx)
+	if err != nil {
+		log.Println("failed to get attachments", err)
+		return
+	}
+
+	for _, e := range entries {
+		if _, err := strconv.Atoi(e.Name()); err != nil || ids[e.Name()] {
+			continue
+		}
+
+		if err := os.Remove(s.attachmentPath(e.Name())); err != nil {
+			log.Println("failed to remove attachment file", err)
+		}
+	}
+}
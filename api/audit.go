@@ -0,0 +1,47 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/zeze322/todo/db"
+	"github.com/zeze322/todo/lib"
+)
+
+// handleGetAudit lists audit entries of one task (id) or of all tasks
+// changed between from and to, both days inclusive.
+func (s *Server) handleGetAudit(w http.ResponseWriter, r *http.Request) error {
+	filter := db.AuditFilter{TaskID: r.FormValue("id")}
+
+	if v := r.FormValue("from"); v != "" {
+		day, err := parseDay(v)
+		if err != nil {
+			return err
+		}
+		filter.From = day
+	}
+
+	if v := r.FormValue("to"); v != "" {
+		day, err := parseDay(v)
+		if err != nil {
+			return err
+		}
+		filter.To = day.AddDate(0, 0, 1)
+	}
+
+	if v := r.FormValue("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 0 {
+			return fmt.Errorf("invalid limit %s", v)
+		}
+		filter.Limit = l
+	}
+
+	entries, err := s.store.GetAudit(r.Context(), filter)
+	if err != nil {
+		return fmt.Errorf("failed to get audit log")
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, db.AuditResponse{Entries: entries})
+}
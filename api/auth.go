@@ -1,17 +1,24 @@
 package api
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/zeze322/todo/db"
 	"github.com/zeze322/todo/lib"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type signRequest struct {
+	// Name is empty or "admin" for the built-in admin, who signs in with
+	// TODO_PASSWORD.
+	Name     string `json:"name"`
 	Password string `json:"password"`
 }
 
@@ -30,11 +37,25 @@ func (s *Server) handleSign(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	if req.Password != s.password {
-		return lib.WriteJSON(w, http.StatusUnauthorized, lib.ApiErr{Error: "invalid password"})
+	if req.Name == "" || strings.EqualFold(req.Name, db.BuiltinAdmin.Name) {
+		if req.Password != s.password {
+			return lib.WriteJSON(w, http.StatusUnauthorized, lib.ApiErr{Error: "invalid password"})
+		}
+
+		token, err := createJWT(req.Password)
+		if err != nil {
+			return err
+		}
+
+		return lib.WriteJSON(w, http.StatusOK, signResponse{Token: token})
 	}
 
-	token, err := createJWT(req.Password)
+	user, err := s.store.GetUserByName(r.Context(), req.Name)
+	if err != nil || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		return lib.WriteJSON(w, http.StatusUnauthorized, lib.ApiErr{Error: "invalid name or password"})
+	}
+
+	token, err := createUserJWT(user)
 	if err != nil {
 		return err
 	}
@@ -42,9 +63,12 @@ func (s *Server) handleSign(w http.ResponseWriter, r *http.Request) error {
 	return lib.WriteJSON(w, http.StatusOK, signResponse{Token: token})
 }
 
-func withJWTAuth(next http.HandlerFunc, password string) http.HandlerFunc {
+// withJWTAuth checks the token cookie and passes the user it was issued
+// for to the storage through the request context. Without TODO_PASSWORD
+// authentication is off and requests act as the built-in admin.
+func (s *Server) withJWTAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if len(password) > 0 {
+		if len(s.password) > 0 {
 
 			var tokenString string
 
@@ -64,30 +88,64 @@ func withJWTAuth(next http.HandlerFunc, password string) http.HandlerFunc {
 				return
 			}
 
-			passwordHash := fmt.Sprintf("%x", sha256.Sum256([]byte(password)))
-
 			claims, ok := token.Claims.(jwt.MapClaims)
 			if !ok {
 				permissionDenied(w)
 				return
 			}
 
-			if passwordHash != claims["passwordHash"] {
+			user, ok := s.tokenUser(r.Context(), claims)
+			if !ok {
 				permissionDenied(w)
 				return
 			}
 
+			r = r.WithContext(db.WithUser(r.Context(), user))
 		}
 		next(w, r)
 	}
 }
 
-func createJWT(password string) (string, error) {
-	passwordHash := fmt.Sprintf("%x", sha256.Sum256([]byte(password)))
-	claims := &jwt.MapClaims{
-		"passwordHash": passwordHash,
+// tokenUser returns the user a token was issued for. Tokens of the
+// built-in admin carry the hash of TODO_PASSWORD, tokens of other users
+// carry their id and a hash of their password hash, so changing either
+// password signs out existing sessions.
+func (s *Server) tokenUser(ctx context.Context, claims jwt.MapClaims) (db.User, bool) {
+	if passwordHash, ok := claims["passwordHash"]; ok {
+		return db.BuiltinAdmin, passwordHash == hash(s.password)
+	}
+
+	id, _ := claims["user_id"].(string)
+	if id == "" || id == db.BuiltinAdmin.ID {
+		return db.User{}, false
 	}
 
+	user, err := s.store.GetUser(ctx, id)
+	if err != nil {
+		return db.User{}, false
+	}
+
+	return user, claims["session"] == hash(user.PasswordHash)
+}
+
+func hash(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
+}
+
+func createJWT(password string) (string, error) {
+	return signJWT(jwt.MapClaims{
+		"passwordHash": hash(password),
+	})
+}
+
+func createUserJWT(user db.User) (string, error) {
+	return signJWT(jwt.MapClaims{
+		"user_id": user.ID,
+		"session": hash(user.PasswordHash),
+	})
+}
+
+func signJWT(claims jwt.MapClaims) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	secret := os.Getenv("TODO_SECRET")
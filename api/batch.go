@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/zeze322/todo/db"
+	"github.com/zeze322/todo/lib"
+)
+
+type batchRequest struct {
+	// Mode is atomic, the default, or best_effort.
+	Mode       db.BatchMode     `json:"mode"`
+	Operations []batchOperation `json:"operations"`
+}
+
+type batchOperation struct {
+	Op string `json:"op"`
+	ID string `json:"id"`
+	// Task is the body of POST /api/task for create and of PUT /api/task
+	// for update.
+	Task      json.RawMessage `json:"task"`
+	ProjectID string          `json:"project_id"`
+	Note      string          `json:"note"`
+	// Version works as the If-Match header of the single requests.
+	Version int `json:"version"`
+}
+
+// handleBatch runs create, update, delete, complete and move operations
+// in one transaction and answers with a result per operation. A failed
+// atomic batch changes nothing and is answered with the error of the
+// failed operation along with the results.
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) error {
+	var req batchRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	if req.Mode == "" {
+		req.Mode = db.BatchAtomic
+	}
+
+	ops := make([]db.BatchOp, len(req.Operations))
+	for i, o := range req.Operations {
+		ops[i] = batchOp(r, o)
+	}
+
+	results, err := s.store.Batch(r.Context(), ops, req.Mode)
+	if results == nil {
+		return err
+	}
+
+	if err == nil {
+		return lib.WriteJSON(w, http.StatusOK, db.BatchResponse{Results: results})
+	}
+
+	status := http.StatusBadRequest
+
+	var (
+		se       *lib.StatusError
+		conflict *db.VersionConflictError
+	)
+
+	if errors.As(err, &se) {
+		status = se.Status
+	} else if errors.As(err, &conflict) {
+		status = http.StatusPreconditionFailed
+	}
+
+	return lib.WriteJSON(w, status, db.BatchResponse{Results: results, Error: err.Error()})
+}
+
+// batchOp validates an operation the way the single request would, an
+// invalid operation fails on its own.
+func batchOp(r *http.Request, o batchOperation) db.BatchOp {
+	op := db.BatchOp{
+		Action:    db.BatchAction(o.Op),
+		ID:        o.ID,
+		ProjectID: o.ProjectID,
+		Note:      o.Note,
+		Version:   o.Version,
+	}
+
+	switch op.Action {
+	case db.BatchCreate:
+		var req db.CreateTaskRequest
+
+		if err := json.Unmarshal(o.Task, &req); err != nil {
+			op.Err = fmt.Errorf("invalid task")
+			return op
+		}
+
+		op.Task, op.Err = newTask(r.Context(), req)
+
+		return op
+	case db.BatchUpdate, db.BatchDelete, db.BatchComplete, db.BatchMove:
+	default:
+		op.Err = fmt.Errorf("unknown operation %s", o.Op)
+		return op
+	}
+
+	if op.ID == "" {
+		op.Err = fmt.Errorf("id not specified")
+		return op
+	}
+
+	if op.Action == db.BatchUpdate {
+		var req db.Task
+
+		if err := json.Unmarshal(o.Task, &req); err != nil {
+			op.Err = fmt.Errorf("invalid task")
+			return op
+		}
+
+		op.Task, op.Err = updatedTask(r.Context(), req)
+	}
+
+	return op
+}
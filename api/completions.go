@@ -0,0 +1,39 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/zeze322/todo/db"
+	"github.com/zeze322/todo/lib"
+)
+
+func (s *Server) handleTaskHistory(w http.ResponseWriter, r *http.Request) error {
+	id := r.FormValue("id")
+	if id == "" {
+		return fmt.Errorf("id not specified")
+	}
+
+	completions, err := s.store.GetCompletions(r.Context(), id)
+	if err != nil {
+		return fmt.Errorf("failed to get task history")
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, db.CompletionsResponse{Completions: completions})
+}
+
+// handleUndoCompletion reverts the last /api/task/done call for a task
+// and responds with the restored task.
+func (s *Server) handleUndoCompletion(w http.ResponseWriter, r *http.Request) error {
+	id := r.FormValue("id")
+	if id == "" {
+		return fmt.Errorf("id not specified")
+	}
+
+	task, err := s.store.UndoCompletion(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, task)
+}
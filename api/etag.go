@@ -0,0 +1,50 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/zeze322/todo/db"
+	"github.com/zeze322/todo/lib"
+)
+
+type conflictResponse struct {
+	Error   string `json:"error"`
+	Version int    `json:"version"`
+}
+
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatch returns the task version required by the If-Match header, zero
+// when the header is missing or "*".
+func ifMatch(r *http.Request) (int, error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(v, "W/"), `"`))
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("invalid If-Match %s", v)
+	}
+
+	return version, nil
+}
+
+// writeConflict answers 412 Precondition Failed with the current version
+// when err is a version conflict and returns any other error as is.
+func writeConflict(w http.ResponseWriter, err error) error {
+	var conflict *db.VersionConflictError
+	if !errors.As(err, &conflict) {
+		return err
+	}
+
+	w.Header().Set("ETag", etag(conflict.Version))
+
+	return lib.WriteJSON(w, http.StatusPreconditionFailed, conflictResponse{Error: conflict.Error(), Version: conflict.Version})
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/zeze322/todo/db"
+	"github.com/zeze322/todo/lib"
+)
+
+func (s *Server) handleField(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case "POST":
+		return s.handleCreateField(w, r)
+	case "PUT":
+		return s.handleUpdateField(w, r)
+	case "DELETE":
+		return s.handleDeleteField(w, r)
+	}
+
+	return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+func (s *Server) handleGetFields(w http.ResponseWriter, r *http.Request) error {
+	fields, err := s.store.GetFields(r.Context())
+	if err != nil {
+		return fmt.Errorf("failed to get fields")
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, db.FieldsResponse{Fields: fields})
+}
+
+func (s *Server) handleCreateField(w http.ResponseWriter, r *http.Request) error {
+	var req db.FieldDefinition
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	field, err := db.NewFieldDefinition("", req.Name, req.Type)
+	if err != nil {
+		return err
+	}
+
+	id, err := s.store.CreateField(r.Context(), field)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, db.CreateTaskResponse{ID: id})
+}
+
+func (s *Server) handleUpdateField(w http.ResponseWriter, r *http.Request) error {
+	var req db.FieldDefinition
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	if req.ID == "" {
+		return fmt.Errorf("id not specified")
+	}
+
+	field, err := db.NewFieldDefinition(req.ID, req.Name, req.Type)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.UpdateField(r.Context(), field); err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, lib.EmptyJSON{})
+}
+
+// handleDeleteField removes a field definition and its value on every task.
+func (s *Server) handleDeleteField(w http.ResponseWriter, r *http.Request) error {
+	id := r.FormValue("id")
+	if id == "" {
+		return fmt.Errorf("id not specified")
+	}
+
+	if err := s.store.DeleteField(r.Context(), id); err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, lib.EmptyJSON{})
+}
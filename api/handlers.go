@@ -1,10 +1,13 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/zeze322/todo/db"
@@ -80,7 +83,7 @@ func (s *Server) handleCreateTask(w http.ResponseWriter, r *http.Request) error
 		return err
 	}
 
-	task, err := db.NewTask(req.Date, req.Title, req.Comment, req.Repeat)
+	task, err := newTask(r.Context(), req)
 	if err != nil {
 		return err
 	}
@@ -95,10 +98,54 @@ func (s *Server) handleCreateTask(w http.ResponseWriter, r *http.Request) error
 	})
 }
 
+// newTask validates a request to create a task.
+func newTask(ctx context.Context, req db.CreateTaskRequest) (db.Task, error) {
+	task, err := db.NewTask(req.Date, req.Title, req.Comment, req.Repeat, req.Deadline)
+	if err != nil {
+		return db.Task{}, err
+	}
+
+	if task.Tags, err = db.NormalizeTags(req.Tags); err != nil {
+		return db.Task{}, err
+	}
+
+	task.ProjectID = req.ProjectID
+	task.Priority = req.Priority
+	task.ParentID = req.ParentID
+	task.AutoComplete = req.AutoComplete
+	task.Fields = req.Fields
+
+	if req.Assignee != "" {
+		assignee := assigneeID(ctx, req.Assignee)
+		task.Assignee = &assignee
+	}
+
+	if task.DeferUntil, err = db.NormalizeDeferDate(&req.DeferUntil); err != nil {
+		return db.Task{}, err
+	}
+
+	if task.BlockedBy, err = db.NormalizeBlockers(req.BlockedBy); err != nil {
+		return db.Task{}, err
+	}
+
+	return task, nil
+}
+
 func (s *Server) handleGetTasks(w http.ResponseWriter, r *http.Request) error {
-	search := r.FormValue("search")
+	filter, err := taskFilter(r)
 
-	tasks, err := s.store.GetTasks(r.Context(), search)
+	var syntaxErr *SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return lib.WriteJSON(w, http.StatusBadRequest, queryErr{Error: syntaxErr.Error(), Position: syntaxErr.Pos})
+	} else if err != nil {
+		return err
+	}
+
+	if err := s.checkFieldNames(r.Context(), filter.Fields); err != nil {
+		return err
+	}
+
+	tasks, err := s.store.GetTasks(r.Context(), filter)
 	if err != nil {
 		return fmt.Errorf("failed to get tasks")
 	}
@@ -106,6 +153,41 @@ func (s *Server) handleGetTasks(w http.ResponseWriter, r *http.Request) error {
 	return lib.WriteJSON(w, http.StatusOK, db.TasksResponse{Tasks: tasks})
 }
 
+// checkFieldNames rejects conditions on fields without a definition,
+// they would silently match nothing.
+func (s *Server) checkFieldNames(ctx context.Context, conditions []db.FieldCondition) error {
+	if len(conditions) == 0 {
+		return nil
+	}
+
+	fields, err := s.store.GetFields(ctx)
+	if err != nil {
+		return err
+	}
+
+	defined := map[string]bool{}
+	for _, f := range fields {
+		defined[f.Name] = true
+	}
+
+	for _, c := range conditions {
+		if !defined[strings.ToLower(c.Name)] {
+			return fmt.Errorf("unknown field %s", c.Name)
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) handleGetTags(w http.ResponseWriter, r *http.Request) error {
+	tags, err := s.store.GetTags(r.Context())
+	if err != nil {
+		return fmt.Errorf("failed to get tags")
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, db.TagsResponse{Tags: tags})
+}
+
 func (s *Server) handleDeleteTask(w http.ResponseWriter, r *http.Request) error {
 	id := r.FormValue("id")
 	if id == "" {
@@ -130,6 +212,8 @@ func (s *Server) handleGetTaskByID(w http.ResponseWriter, r *http.Request) error
 		return err
 	}
 
+	w.Header().Set("ETag", etag(task.Version))
+
 	return lib.WriteJSON(w, http.StatusOK, task)
 }
 
@@ -140,47 +224,218 @@ func (s *Server) handleUpdateTask(w http.ResponseWriter, r *http.Request) error
 		return err
 	}
 
-	updateTask, err := db.NewTask(req.Date, req.Title, req.Comment, req.Repeat)
+	updateTask, err := updatedTask(r.Context(), req)
 	if err != nil {
 		return err
 	}
 
-	if err := s.store.UpdateTask(r.Context(), req.ID, updateTask); err != nil {
+	if updateTask.Version, err = ifMatch(r); err != nil {
 		return err
 	}
 
+	if err := s.store.UpdateTask(r.Context(), req.ID, updateTask); err != nil {
+		return writeConflict(w, err)
+	}
+
 	return lib.WriteJSON(w, http.StatusOK, lib.EmptyJSON{})
 }
 
+// updatedTask validates a request to update a task.
+func updatedTask(ctx context.Context, req db.Task) (db.Task, error) {
+	var deadline string
+	if req.Deadline != nil {
+		deadline = *req.Deadline
+	}
+
+	updateTask, err := db.NewTask(req.Date, req.Title, req.Comment, req.Repeat, deadline)
+	if err != nil {
+		return db.Task{}, err
+	}
+
+	if req.Deadline != nil && *req.Deadline == "" {
+		updateTask.Deadline = req.Deadline
+	}
+
+	if updateTask.Tags, err = db.NormalizeTags(req.Tags); err != nil {
+		return db.Task{}, err
+	}
+
+	updateTask.ProjectID = req.ProjectID
+	updateTask.Priority = req.Priority
+	updateTask.AutoComplete = req.AutoComplete
+	updateTask.Fields = req.Fields
+
+	if req.Assignee != nil {
+		assignee := assigneeID(ctx, *req.Assignee)
+		updateTask.Assignee = &assignee
+	}
+
+	if updateTask.DeferUntil, err = db.NormalizeDeferDate(req.DeferUntil); err != nil {
+		return db.Task{}, err
+	}
+
+	if updateTask.BlockedBy, err = db.NormalizeBlockers(req.BlockedBy); err != nil {
+		return db.Task{}, err
+	}
+
+	return updateTask, nil
+}
+
 func (s *Server) handleTaskDone(w http.ResponseWriter, r *http.Request) error {
 	id := r.FormValue("id")
 	if id == "" {
 		return fmt.Errorf("id not specified")
 	}
 
-	task, err := s.store.GetTask(r.Context(), id)
+	version, err := ifMatch(r)
 	if err != nil {
 		return err
 	}
 
-	if task.Repeat == "" {
-		if err := s.store.DeleteTask(r.Context(), id); err != nil {
-			return err
+	if _, err := s.store.CompleteTask(r.Context(), id, r.FormValue("note"), version); err != nil {
+		return writeConflict(w, err)
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, lib.EmptyJSON{})
+}
+
+// taskFilter reads the GET /api/tasks query parameters. search is a
+// query in the language described at parseQuery, the other parameters
+// set single filter fields directly.
+func taskFilter(r *http.Request) (db.TaskFilter, error) {
+	var (
+		filter db.TaskFilter
+		err    error
+	)
+
+	if err := parseQuery(r.FormValue("search"), &filter); err != nil {
+		return db.TaskFilter{}, err
+	}
+
+	// the parameters narrow the dates of the search query
+	for _, param := range []struct {
+		name, key          string
+		rangeFrom, rangeTo *string
+	}{
+		{"from", "from", &filter.From, &filter.To},
+		{"to", "to", &filter.From, &filter.To},
+		{"deadline_from", "from", &filter.DeadlineFrom, &filter.DeadlineTo},
+		{"deadline_to", "to", &filter.DeadlineFrom, &filter.DeadlineTo},
+	} {
+		if v := r.FormValue(param.name); v != "" {
+			date, err := parseDate(v)
+			if err != nil {
+				return db.TaskFilter{}, err
+			}
+
+			restrictDates(param.rangeFrom, param.rangeTo, param.key, date)
 		}
 	}
 
-	if task.Repeat != "" {
-		next, err := repeattask.UpdateDate(task.Date, task.Repeat)
+	if within := r.FormValue("due_within"); within != "" {
+		days, err := parsePeriod(within)
 		if err != nil {
-			return err
+			return db.TaskFilter{}, err
 		}
 
-		task.Date = next
+		restrictDates(&filter.DeadlineFrom, &filter.DeadlineTo, "to", time.Now().AddDate(0, 0, days).Format(lib.Layout))
+	}
 
-		if err := s.store.UpdateTask(r.Context(), id, task); err != nil {
-			return err
+	switch repeat := r.FormValue("repeat"); repeat {
+	case "":
+	case "true":
+		filter.Repeat = db.RepeatOnly
+	case "false":
+		filter.Repeat = db.RepeatNone
+	default:
+		return db.TaskFilter{}, fmt.Errorf("invalid repeat value %s", repeat)
+	}
+
+	switch blocked := r.FormValue("blocked"); blocked {
+	case "":
+	case "true":
+		filter.Blocked = db.BlockedOnly
+	case "false":
+		filter.Blocked = db.BlockedNone
+	default:
+		return db.TaskFilter{}, fmt.Errorf("invalid blocked value %s", blocked)
+	}
+
+	if deferred := r.FormValue("include_deferred"); deferred != "" {
+		if filter.IncludeDeferred, err = strconv.ParseBool(deferred); err != nil {
+			return db.TaskFilter{}, fmt.Errorf("invalid include_deferred value %s", deferred)
 		}
 	}
 
-	return lib.WriteJSON(w, http.StatusOK, lib.EmptyJSON{})
+	if overdue := r.FormValue("overdue"); overdue != "" {
+		if filter.Overdue, err = strconv.ParseBool(overdue); err != nil {
+			return db.TaskFilter{}, fmt.Errorf("invalid overdue value %s", overdue)
+		}
+	}
+
+	filter.ProjectID = r.FormValue("project")
+	filter.ParentID = r.FormValue("parent")
+	filter.Assignee = assigneeID(r.Context(), r.FormValue("assignee"))
+
+	if r.Form["tag"] != nil {
+		tags, err := db.NormalizeTags(r.Form["tag"])
+		if err != nil {
+			return db.TaskFilter{}, err
+		}
+		filter.Tags = append(filter.Tags, tags...)
+	}
+
+	for _, field := range r.Form["field"] {
+		c, err := parseFieldCondition(field)
+		if err != nil {
+			return db.TaskFilter{}, err
+		}
+		filter.Fields = append(filter.Fields, c)
+	}
+
+	if filter.Sort, err = db.ParseSort(r.FormValue("sort")); err != nil {
+		return db.TaskFilter{}, err
+	}
+
+	if l := r.FormValue("limit"); l != "" {
+		if filter.Limit, err = strconv.Atoi(l); err != nil {
+			return db.TaskFilter{}, fmt.Errorf("invalid limit %s", l)
+		}
+	}
+
+	return filter, filter.Validate()
+}
+
+// parsePeriod reads a number of days written as 3d or 2w.
+func parsePeriod(s string) (int, error) {
+	if len(s) < 2 {
+		return 0, fmt.Errorf("invalid period %s", s)
+	}
+
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid period %s", s)
+	}
+
+	switch s[len(s)-1] {
+	case 'd':
+		return n, nil
+	case 'w':
+		return n * 7, nil
+	}
+
+	return 0, fmt.Errorf("invalid period %s", s)
+}
+
+// parseDate accepts both the storage layout and the DD.MM.YYYY format.
+func parseDate(s string) (string, error) {
+	if lib.IsDate(s) {
+		return lib.ParseTime(s)
+	}
+
+	if _, err := time.Parse(lib.Layout, s); err != nil {
+		return "", fmt.Errorf("invalid date")
+	}
+
+	return s, nil
 }
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/zeze322/todo/db"
+	"github.com/zeze322/todo/lib"
+)
+
+type memberRequest struct {
+	ProjectID string `json:"project_id"`
+	// Name invites a user, UserID picks an existing member.
+	Name   string `json:"name"`
+	UserID string `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// handleMembers lists the members of a project with GET ?id=, invites a
+// user with POST, changes a role with PUT and revokes access with
+// DELETE ?id=&user=.
+func (s *Server) handleMembers(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case "GET":
+		return s.handleGetMembers(w, r)
+	case "POST":
+		return s.handleInviteMember(w, r)
+	case "PUT":
+		return s.handleUpdateMember(w, r)
+	case "DELETE":
+		return s.handleRevokeMember(w, r)
+	}
+
+	return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+func (s *Server) handleGetMembers(w http.ResponseWriter, r *http.Request) error {
+	id := r.FormValue("id")
+	if id == "" {
+		return fmt.Errorf("id not specified")
+	}
+
+	members, err := s.store.GetMembers(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, db.MembersResponse{Members: members})
+}
+
+func (s *Server) handleInviteMember(w http.ResponseWriter, r *http.Request) error {
+	var req memberRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	if req.ProjectID == "" {
+		return fmt.Errorf("project_id not specified")
+	}
+
+	if req.Name == "" {
+		return fmt.Errorf("name not specified")
+	}
+
+	role, err := db.ParseMemberRole(req.Role)
+	if err != nil {
+		return err
+	}
+
+	member, err := s.store.ShareProject(r.Context(), req.ProjectID, req.Name, role)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, member)
+}
+
+func (s *Server) handleUpdateMember(w http.ResponseWriter, r *http.Request) error {
+	var req memberRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	if req.ProjectID == "" || req.UserID == "" {
+		return fmt.Errorf("project_id and user_id should be specified")
+	}
+
+	role, err := db.ParseMemberRole(req.Role)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.SetMemberRole(r.Context(), req.ProjectID, req.UserID, role); err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, lib.EmptyJSON{})
+}
+
+func (s *Server) handleRevokeMember(w http.ResponseWriter, r *http.Request) error {
+	id, user := r.FormValue("id"), r.FormValue("user")
+	if id == "" || user == "" {
+		return fmt.Errorf("id and user should be specified")
+	}
+
+	if err := s.store.RevokeMember(r.Context(), id, user); err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, lib.EmptyJSON{})
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/zeze322/todo/db"
+	"github.com/zeze322/todo/lib"
+)
+
+func (s *Server) handleNotes(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case "GET":
+		return s.handleGetNotes(w, r)
+	case "POST":
+		return s.handleCreateNote(w, r)
+	case "PUT":
+		return s.handleUpdateNote(w, r)
+	case "DELETE":
+		return s.handleDeleteNote(w, r)
+	}
+
+	return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+func (s *Server) handleGetNotes(w http.ResponseWriter, r *http.Request) error {
+	id := r.FormValue("id")
+	if id == "" {
+		return fmt.Errorf("id not specified")
+	}
+
+	notes, err := s.store.GetNotes(r.Context(), id)
+	if err != nil {
+		return fmt.Errorf("failed to get notes")
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, db.NotesResponse{Notes: notes})
+}
+
+func (s *Server) handleCreateNote(w http.ResponseWriter, r *http.Request) error {
+	var req db.Note
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	if req.TaskID == "" {
+		return fmt.Errorf("task_id not specified")
+	}
+
+	note, err := db.NewNote("", req.TaskID, req.Text)
+	if err != nil {
+		return err
+	}
+
+	id, err := s.store.CreateNote(r.Context(), note)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, db.CreateTaskResponse{ID: id})
+}
+
+func (s *Server) handleUpdateNote(w http.ResponseWriter, r *http.Request) error {
+	var req db.Note
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	if req.ID == "" {
+		return fmt.Errorf("id not specified")
+	}
+
+	note, err := db.NewNote(req.ID, "", req.Text)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.UpdateNote(r.Context(), note); err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, lib.EmptyJSON{})
+}
+
+func (s *Server) handleDeleteNote(w http.ResponseWriter, r *http.Request) error {
+	id := r.FormValue("note")
+	if id == "" {
+		return fmt.Errorf("note not specified")
+	}
+
+	if err := s.store.DeleteNote(r.Context(), id); err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, lib.EmptyJSON{})
+}
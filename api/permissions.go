@@ -0,0 +1,321 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/zeze322/todo/db"
+	"github.com/zeze322/todo/lib"
+)
+
+// guardedStorage checks the role of the authenticated user on a project
+// before every change to the project or its tasks. Reads are left to the
+// storage, which only returns what the user can see. ShiftTasks is the one
+// change checked by the storage: it picks its tasks by date range in the
+// same transaction that moves them, so it only selects tasks of projects
+// the user can edit instead of checking them up front here.
+type guardedStorage struct {
+	db.Storage
+}
+
+func errForbidden(role string) error {
+	return &lib.StatusError{Status: http.StatusForbidden, Err: fmt.Errorf("%s role required", role)}
+}
+
+func (g guardedStorage) requireProject(ctx context.Context, id string, need db.Role) error {
+	// an empty id stands for the Inbox of the user
+	if id == "" {
+		return nil
+	}
+
+	role, err := g.Storage.ProjectRole(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !role.Allows(need) {
+		return errForbidden(string(need))
+	}
+
+	return nil
+}
+
+func requireAdmin(ctx context.Context) error {
+	if !db.UserFrom(ctx).Admin {
+		return errForbidden("admin")
+	}
+
+	return nil
+}
+
+func (g guardedStorage) requireTask(ctx context.Context, id string, need db.Role) error {
+	role, err := g.Storage.TaskRole(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !role.Allows(need) {
+		return errForbidden(string(need))
+	}
+
+	return nil
+}
+
+// canCreate checks the role on the parent of a subtask or on the project
+// of a top-level task.
+func (g guardedStorage) canCreate(ctx context.Context, task db.Task) error {
+	if task.ParentID != "" {
+		return g.requireTask(ctx, task.ParentID, db.RoleEditor)
+	}
+
+	return g.requireProject(ctx, task.ProjectID, db.RoleEditor)
+}
+
+// canUpdate checks the role on the task and on the project it moves to.
+func (g guardedStorage) canUpdate(ctx context.Context, id, projectID string) error {
+	if err := g.requireTask(ctx, id, db.RoleEditor); err != nil {
+		return err
+	}
+
+	return g.requireProject(ctx, projectID, db.RoleEditor)
+}
+
+func (g guardedStorage) canComplete(ctx context.Context, id string) error {
+	if err := g.requireTask(ctx, id, db.RoleEditor); err != nil {
+		return err
+	}
+
+	task, err := g.Storage.GetTask(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !db.CanComplete(ctx, task) {
+		return &lib.StatusError{Status: http.StatusForbidden, Err: fmt.Errorf("only the assignee or the assigner can complete the task")}
+	}
+
+	return nil
+}
+
+func (g guardedStorage) CreateTask(ctx context.Context, task db.Task) (string, error) {
+	if err := g.canCreate(ctx, task); err != nil {
+		return "", err
+	}
+
+	return g.Storage.CreateTask(ctx, task)
+}
+
+func (g guardedStorage) UpdateTask(ctx context.Context, id string, task db.Task) error {
+	if err := g.canUpdate(ctx, id, task.ProjectID); err != nil {
+		return err
+	}
+
+	return g.Storage.UpdateTask(ctx, id, task)
+}
+
+func (g guardedStorage) DeleteTask(ctx context.Context, id string) error {
+	if err := g.requireTask(ctx, id, db.RoleEditor); err != nil {
+		return err
+	}
+
+	return g.Storage.DeleteTask(ctx, id)
+}
+
+func (g guardedStorage) RestoreTask(ctx context.Context, id string) error {
+	if err := g.requireTask(ctx, id, db.RoleEditor); err != nil {
+		return err
+	}
+
+	return g.Storage.RestoreTask(ctx, id)
+}
+
+func (g guardedStorage) UncompleteTask(ctx context.Context, id string) error {
+	if err := g.requireTask(ctx, id, db.RoleEditor); err != nil {
+		return err
+	}
+
+	return g.Storage.UncompleteTask(ctx, id)
+}
+
+func (g guardedStorage) CompleteTask(ctx context.Context, id, note string, version int) (db.Task, error) {
+	if err := g.canComplete(ctx, id); err != nil {
+		return db.Task{}, err
+	}
+
+	return g.Storage.CompleteTask(ctx, id, note, version)
+}
+
+// Batch checks every operation up front, the ones the user isn't allowed
+// to run fail on their own, or fail the whole batch in atomic mode.
+func (g guardedStorage) Batch(ctx context.Context, ops []db.BatchOp, mode db.BatchMode) ([]db.BatchResult, error) {
+	for i, op := range ops {
+		if op.Err != nil {
+			continue
+		}
+
+		switch op.Action {
+		case db.BatchCreate:
+			ops[i].Err = g.canCreate(ctx, op.Task)
+		case db.BatchUpdate:
+			ops[i].Err = g.canUpdate(ctx, op.ID, op.Task.ProjectID)
+		case db.BatchDelete:
+			ops[i].Err = g.requireTask(ctx, op.ID, db.RoleEditor)
+		case db.BatchComplete:
+			ops[i].Err = g.canComplete(ctx, op.ID)
+		case db.BatchMove:
+			ops[i].Err = g.canUpdate(ctx, op.ID, op.ProjectID)
+		}
+	}
+
+	return g.Storage.Batch(ctx, ops, mode)
+}
+
+func (g guardedStorage) UndoCompletion(ctx context.Context, id string) (db.Task, error) {
+	if err := g.requireTask(ctx, id, db.RoleEditor); err != nil {
+		return db.Task{}, err
+	}
+
+	return g.Storage.UndoCompletion(ctx, id)
+}
+
+func (g guardedStorage) CreateAttachment(ctx context.Context, a db.Attachment) (string, error) {
+	if err := g.requireTask(ctx, a.TaskID, db.RoleEditor); err != nil {
+		return "", err
+	}
+
+	return g.Storage.CreateAttachment(ctx, a)
+}
+
+func (g guardedStorage) DeleteAttachment(ctx context.Context, id string) error {
+	a, err := g.Storage.GetAttachment(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := g.requireTask(ctx, a.TaskID, db.RoleEditor); err != nil {
+		return err
+	}
+
+	return g.Storage.DeleteAttachment(ctx, id)
+}
+
+func (g guardedStorage) CreateNote(ctx context.Context, n db.Note) (string, error) {
+	if err := g.requireTask(ctx, n.TaskID, db.RoleEditor); err != nil {
+		return "", err
+	}
+
+	return g.Storage.CreateNote(ctx, n)
+}
+
+// UpdateNote and DeleteNote keep the author check of the storage and add
+// the editor role, so a revoked or viewer member can't touch old notes.
+func (g guardedStorage) UpdateNote(ctx context.Context, n db.Note) error {
+	if err := g.requireNote(ctx, n.ID); err != nil {
+		return err
+	}
+
+	return g.Storage.UpdateNote(ctx, n)
+}
+
+func (g guardedStorage) DeleteNote(ctx context.Context, id string) error {
+	if err := g.requireNote(ctx, id); err != nil {
+		return err
+	}
+
+	return g.Storage.DeleteNote(ctx, id)
+}
+
+func (g guardedStorage) requireNote(ctx context.Context, id string) error {
+	n, err := g.Storage.GetNote(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return g.requireTask(ctx, n.TaskID, db.RoleEditor)
+}
+
+// Field definitions are shared by all users, deleting one drops its
+// values from every task, so only an admin manages them.
+func (g guardedStorage) CreateField(ctx context.Context, f db.FieldDefinition) (string, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return "", err
+	}
+
+	return g.Storage.CreateField(ctx, f)
+}
+
+func (g guardedStorage) UpdateField(ctx context.Context, f db.FieldDefinition) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	return g.Storage.UpdateField(ctx, f)
+}
+
+func (g guardedStorage) DeleteField(ctx context.Context, id string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	return g.Storage.DeleteField(ctx, id)
+}
+
+func (g guardedStorage) UpdateProject(ctx context.Context, p db.Project) error {
+	if err := g.requireProject(ctx, p.ID, db.RoleOwner); err != nil {
+		return err
+	}
+
+	return g.Storage.UpdateProject(ctx, p)
+}
+
+// DeleteProject needs the owner role on the project and, as its tasks are
+// moved there, the editor role on the target.
+func (g guardedStorage) DeleteProject(ctx context.Context, id string, mode db.ProjectDeleteMode, target string) error {
+	if err := g.requireProject(ctx, id, db.RoleOwner); err != nil {
+		return err
+	}
+
+	if err := g.requireProject(ctx, target, db.RoleEditor); err != nil {
+		return err
+	}
+
+	return g.Storage.DeleteProject(ctx, id, mode, target)
+}
+
+func (g guardedStorage) ShareProject(ctx context.Context, id, name string, role db.Role) (db.Member, error) {
+	if err := g.requireProject(ctx, id, db.RoleOwner); err != nil {
+		return db.Member{}, err
+	}
+
+	return g.Storage.ShareProject(ctx, id, name, role)
+}
+
+func (g guardedStorage) SetMemberRole(ctx context.Context, id, userID string, role db.Role) error {
+	if err := g.requireProject(ctx, id, db.RoleOwner); err != nil {
+		return err
+	}
+
+	return g.Storage.SetMemberRole(ctx, id, userID, role)
+}
+
+// RevokeMember lets members leave a project, removing others is up to
+// the owner.
+func (g guardedStorage) RevokeMember(ctx context.Context, id, userID string) error {
+	if userID != db.UserFrom(ctx).ID {
+		if err := g.requireProject(ctx, id, db.RoleOwner); err != nil {
+			return err
+		}
+	}
+
+	return g.Storage.RevokeMember(ctx, id, userID)
+}
+
+// assigneeID resolves "me" to the id of the authenticated user.
+func assigneeID(ctx context.Context, assignee string) string {
+	if assignee == "me" {
+		return db.UserFrom(ctx).ID
+	}
+
+	return assignee
+}
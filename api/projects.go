@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/zeze322/todo/db"
+	"github.com/zeze322/todo/lib"
+)
+
+func (s *Server) handleProject(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case "POST":
+		return s.handleCreateProject(w, r)
+	case "PUT":
+		return s.handleUpdateProject(w, r)
+	case "DELETE":
+		return s.handleDeleteProject(w, r)
+	}
+
+	return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+func (s *Server) handleGetProjects(w http.ResponseWriter, r *http.Request) error {
+	projects, err := s.store.GetProjects(r.Context())
+	if err != nil {
+		return fmt.Errorf("failed to get projects")
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, db.ProjectsResponse{Projects: projects})
+}
+
+func (s *Server) handleCreateProject(w http.ResponseWriter, r *http.Request) error {
+	var req db.Project
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	project, err := db.NewProject("", req.Name)
+	if err != nil {
+		return err
+	}
+
+	id, err := s.store.CreateProject(r.Context(), project)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, db.CreateTaskResponse{ID: id})
+}
+
+func (s *Server) handleUpdateProject(w http.ResponseWriter, r *http.Request) error {
+	var req db.Project
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	if req.ID == "" {
+		return fmt.Errorf("id not specified")
+	}
+
+	project, err := db.NewProject(req.ID, req.Name)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.UpdateProject(r.Context(), project); err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, lib.EmptyJSON{})
+}
+
+// handleDeleteProject deletes a project. mode=reassign (the default)
+// moves its tasks to the project given in to, or to the Inbox;
+// mode=cascade moves them to the trash.
+func (s *Server) handleDeleteProject(w http.ResponseWriter, r *http.Request) error {
+	id := r.FormValue("id")
+	if id == "" {
+		return fmt.Errorf("id not specified")
+	}
+
+	mode := db.ProjectDeleteMode(r.FormValue("mode"))
+	if mode == "" {
+		mode = db.ProjectReassign
+	}
+
+	if err := s.store.DeleteProject(r.Context(), id, mode, r.FormValue("to")); err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, lib.EmptyJSON{})
+}
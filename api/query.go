@@ -0,0 +1,361 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/zeze322/todo/db"
+	"github.com/zeze322/todo/lib"
+)
+
+// SyntaxError reports a malformed search query. Pos is the offset of the
+// offending token in characters, counting from zero.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+type queryErr struct {
+	Error    string `json:"error"`
+	Position int    `json:"position"`
+}
+
+// parseQuery applies a search query such as
+//
+//	invoice before:01.12.2026 repeat:m -draft title:"march report"
+//
+// to filter. Bare words and quoted phrases are full-text terms, a leading
+// minus excludes a term, and key:value pairs restrict other fields:
+//
+//	before:DATE, after:DATE, on:DATE  task date, a bare date means on:
+//	due_before:DATE, due_after:DATE, due:DATE
+//	                                  deadline
+//	repeat:yes|no|d|w|m|y             repeating tasks or a rule kind
+//	title:TEXT, comment:TEXT          text in one field only
+//	tag:NAME or #NAME                 tasks with a tag
+//	project:NAME                      tasks in a project
+//	priority:none|low|medium|high|urgent
+//	is:overdue                        tasks scheduled before today
+//	is:blocked, is:ready              tasks with or without open blockers
+//	field.NAME>VALUE                  custom field comparison, with one of
+//	                                  = != > >= < <=, a colon means =
+//
+// Dates are written as DD.MM.YYYY or YYYYMMDD.
+func parseQuery(query string, filter *db.TaskFilter) error {
+	p := queryParser{input: []rune(query), filter: filter}
+
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.input) {
+			return nil
+		}
+
+		if err := p.parseTerm(); err != nil {
+			return err
+		}
+	}
+}
+
+type queryParser struct {
+	input  []rune
+	pos    int
+	filter *db.TaskFilter
+}
+
+func (p *queryParser) parseTerm() error {
+	start := p.pos
+
+	negate := p.input[p.pos] == '-'
+	if negate {
+		p.pos++
+		if p.pos >= len(p.input) || unicode.IsSpace(p.input[p.pos]) {
+			return &SyntaxError{Pos: start, Msg: "nothing to exclude after '-'"}
+		}
+	}
+
+	if p.input[p.pos] == '"' {
+		phrase, err := p.readQuoted()
+		if err != nil {
+			return err
+		}
+
+		p.filter.Terms = append(p.filter.Terms, db.TextTerm{Value: phrase, Phrase: true, Negate: negate})
+
+		return nil
+	}
+
+	keyStart := p.pos
+	for p.pos < len(p.input) && !unicode.IsSpace(p.input[p.pos]) && p.input[p.pos] != ':' && p.input[p.pos] != '"' {
+		p.pos++
+	}
+
+	key := string(p.input[keyStart:p.pos])
+
+	if p.pos < len(p.input) && p.input[p.pos] == ':' && isOperator(key) {
+		p.pos++
+
+		valuePos := p.pos
+		value, phrase, err := p.readValue()
+		if err != nil {
+			return err
+		}
+
+		if value == "" {
+			return &SyntaxError{Pos: valuePos, Msg: fmt.Sprintf("missing value for %s", key)}
+		}
+
+		return p.applyOperator(start, strings.ToLower(key), value, valuePos, phrase, negate)
+	}
+
+	// not an operator, so the whole token is a plain word, e.g. 18:00
+	p.pos = keyStart
+	for p.pos < len(p.input) && !unicode.IsSpace(p.input[p.pos]) {
+		if p.input[p.pos] == '"' {
+			return &SyntaxError{Pos: p.pos, Msg: "unexpected quote"}
+		}
+		p.pos++
+	}
+
+	word := string(p.input[keyStart:p.pos])
+
+	if len(word) > 1 && word[0] == '#' {
+		return p.applyOperator(start, "tag", word[1:], keyStart+1, false, negate)
+	}
+
+	if strings.HasPrefix(strings.ToLower(word), fieldPrefix) {
+		if negate {
+			return &SyntaxError{Pos: start, Msg: "field conditions cannot be excluded"}
+		}
+
+		c, err := parseFieldCondition(word[len(fieldPrefix):])
+		if err != nil {
+			return &SyntaxError{Pos: keyStart, Msg: err.Error()}
+		}
+
+		p.filter.Fields = append(p.filter.Fields, c)
+
+		return nil
+	}
+
+	if lib.IsDate(word) && !negate {
+		return p.applyOperator(start, "on", word, keyStart, false, false)
+	}
+
+	p.filter.Terms = append(p.filter.Terms, db.TextTerm{Value: word, Negate: negate})
+
+	return nil
+}
+
+func (p *queryParser) applyOperator(start int, key, value string, valuePos int, phrase, negate bool) error {
+	switch key {
+	case "title", "comment":
+		p.filter.Terms = append(p.filter.Terms, db.TextTerm{Field: key, Value: value, Phrase: phrase, Negate: negate})
+		return nil
+	case "tag":
+		tags, err := db.NormalizeTags([]string{value})
+		if err != nil {
+			return &SyntaxError{Pos: valuePos, Msg: err.Error()}
+		}
+
+		if negate {
+			p.filter.ExcludeTags = append(p.filter.ExcludeTags, tags...)
+		} else {
+			p.filter.Tags = append(p.filter.Tags, tags...)
+		}
+
+		return nil
+	}
+
+	if negate {
+		return &SyntaxError{Pos: start, Msg: fmt.Sprintf("%s: cannot be excluded", key)}
+	}
+
+	switch key {
+	case "before", "after", "on":
+		date, err := parseDate(value)
+		if err != nil {
+			return &SyntaxError{Pos: valuePos, Msg: fmt.Sprintf("invalid date %s", value)}
+		}
+
+		restrictDates(&p.filter.From, &p.filter.To, key, date)
+	case "due_before", "due_after", "due":
+		date, err := parseDate(value)
+		if err != nil {
+			return &SyntaxError{Pos: valuePos, Msg: fmt.Sprintf("invalid date %s", value)}
+		}
+
+		restrictDates(&p.filter.DeadlineFrom, &p.filter.DeadlineTo, strings.TrimPrefix(key, "due_"), date)
+	case "repeat":
+		switch v := strings.ToLower(value); v {
+		case "yes":
+			p.filter.Repeat = db.RepeatOnly
+		case "no":
+			p.filter.Repeat = db.RepeatNone
+		case "d", "w", "m", "y":
+			p.filter.RepeatRule = v
+		default:
+			return &SyntaxError{Pos: valuePos, Msg: fmt.Sprintf("unknown repeat value %s", value)}
+		}
+	case "project":
+		p.filter.ProjectName = value
+	case "priority":
+		v := strings.ToLower(value)
+		if _, err := db.ParsePriority(v); err != nil {
+			return &SyntaxError{Pos: valuePos, Msg: err.Error()}
+		}
+
+		p.filter.Priority = v
+	case "is":
+		switch strings.ToLower(value) {
+		case "overdue":
+			p.filter.Overdue = true
+		case "blocked":
+			p.filter.Blocked = db.BlockedOnly
+		case "ready":
+			p.filter.Blocked = db.BlockedNone
+		default:
+			return &SyntaxError{Pos: valuePos, Msg: fmt.Sprintf("unknown is: value %s", value)}
+		}
+	}
+
+	return nil
+}
+
+// restrictDates narrows a date range so that several date operators in
+// one query are combined with AND. Besides the operators key can be from
+// or to, inclusive bounds of the range.
+func restrictDates(rangeFrom, rangeTo *string, key, date string) {
+	from, to := date, date
+
+	switch key {
+	case "before":
+		from = ""
+		to = shiftDate(date, -1)
+	case "after":
+		from = shiftDate(date, 1)
+		to = ""
+	case "from":
+		to = ""
+	case "to":
+		from = ""
+	}
+
+	if from != "" && from > *rangeFrom {
+		*rangeFrom = from
+	}
+
+	if to != "" && (*rangeTo == "" || to < *rangeTo) {
+		*rangeTo = to
+	}
+}
+
+func (p *queryParser) readValue() (string, bool, error) {
+	if p.pos < len(p.input) && p.input[p.pos] == '"' {
+		value, err := p.readQuoted()
+		return value, true, err
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && !unicode.IsSpace(p.input[p.pos]) {
+		if p.input[p.pos] == '"' {
+			return "", false, &SyntaxError{Pos: p.pos, Msg: "unexpected quote"}
+		}
+		p.pos++
+	}
+
+	return string(p.input[start:p.pos]), false, nil
+}
+
+func (p *queryParser) readQuoted() (string, error) {
+	open := p.pos
+	p.pos++
+
+	for p.pos < len(p.input) && p.input[p.pos] != '"' {
+		p.pos++
+	}
+
+	if p.pos >= len(p.input) {
+		return "", &SyntaxError{Pos: open, Msg: "unterminated quote"}
+	}
+
+	value := strings.TrimSpace(string(p.input[open+1 : p.pos]))
+	p.pos++
+
+	if value == "" {
+		return "", &SyntaxError{Pos: open, Msg: "empty phrase"}
+	}
+
+	return value, nil
+}
+
+func (p *queryParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+var operators = map[string]bool{
+	"before":     true,
+	"after":      true,
+	"on":         true,
+	"due":        true,
+	"due_before": true,
+	"due_after":  true,
+	"repeat":     true,
+	"title":      true,
+	"comment":    true,
+	"tag":        true,
+	"project":    true,
+	"priority":   true,
+	"is":         true,
+}
+
+func isOperator(key string) bool {
+	return operators[strings.ToLower(key)]
+}
+
+const fieldPrefix = "field."
+
+// parseFieldCondition parses a custom field comparison such as cost>100
+// or due<=01.12.2026, dates are converted to the storage layout.
+func parseFieldCondition(s string) (db.FieldCondition, error) {
+	i := strings.IndexAny(s, "=!<>:")
+	if i <= 0 {
+		return db.FieldCondition{}, fmt.Errorf("invalid field condition %s", s)
+	}
+
+	name, rest := s[:i], s[i:]
+
+	for _, op := range []string{">=", "<=", "!=", "=", ">", "<", ":"} {
+		if !strings.HasPrefix(rest, op) {
+			continue
+		}
+
+		value := rest[len(op):]
+		if value == "" {
+			return db.FieldCondition{}, fmt.Errorf("missing value after %s%s", name, op)
+		}
+		if lib.IsDate(value) {
+			value, _ = lib.ParseTime(value)
+		}
+
+		if op == ":" {
+			op = "="
+		}
+
+		return db.FieldCondition{Name: name, Op: op, Value: value}, nil
+	}
+
+	return db.FieldCondition{}, fmt.Errorf("invalid field condition %s", s)
+}
+
+func shiftDate(date string, days int) string {
+	t, _ := time.Parse(lib.Layout, date)
+	return t.AddDate(0, 0, days).Format(lib.Layout)
+}
@@ -1,9 +1,12 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/zeze322/todo/db"
@@ -11,16 +14,20 @@ import (
 )
 
 type Server struct {
-	password string
-	port     string
-	store    db.Storage
+	password       string
+	port           string
+	trashRetention time.Duration
+	attachments    AttachmentConfig
+	store          db.Storage
 }
 
-func NewServer(port, password string, store db.Storage) *Server {
+func NewServer(port, password string, trashRetention time.Duration, attachments AttachmentConfig, store db.Storage) *Server {
 	return &Server{
-		port:     port,
-		password: password,
-		store:    store,
+		port:           port,
+		password:       password,
+		trashRetention: trashRetention,
+		attachments:    attachments,
+		store:          guardedStorage{store},
 	}
 }
 
@@ -30,11 +37,38 @@ func (s *Server) Run() error {
 	router.Handle("/*", http.FileServer(http.Dir("./web")))
 
 	router.HandleFunc("/api/signin", lib.MakeHTTP(s.handleSign))
-	router.HandleFunc("/api/tasks", withJWTAuth(lib.MakeHTTP(s.handleTask), s.password))
-	router.HandleFunc("/api/task", withJWTAuth(lib.MakeHTTP(s.handleTask), s.password))
-	router.Get("/api/task", withJWTAuth(lib.MakeHTTP(s.handleGetTaskByID), s.password))
-	router.Post("/api/task/done", withJWTAuth(lib.MakeHTTP(s.handleTaskDone), s.password))
+	router.HandleFunc("/api/tasks", s.withJWTAuth(lib.MakeHTTP(s.handleTask)))
+	router.HandleFunc("/api/task", s.withJWTAuth(lib.MakeHTTP(s.handleTask)))
+	router.Get("/api/task", s.withJWTAuth(lib.MakeHTTP(s.handleGetTaskByID)))
+	router.Post("/api/task/done", s.withJWTAuth(lib.MakeHTTP(s.handleTaskDone)))
+	router.HandleFunc("/api/task/attachments", s.withJWTAuth(lib.MakeHTTP(s.handleAttachments)))
+	router.HandleFunc("/api/task/notes", s.withJWTAuth(lib.MakeHTTP(s.handleNotes)))
+	router.Get("/api/task/history", s.withJWTAuth(lib.MakeHTTP(s.handleTaskHistory)))
+	router.Post("/api/task/undo", s.withJWTAuth(lib.MakeHTTP(s.handleUndoCompletion)))
+	router.Post("/api/task/uncomplete", s.withJWTAuth(lib.MakeHTTP(s.handleUncompleteTask)))
+	router.Post("/api/tasks/batch", s.withJWTAuth(lib.MakeHTTP(s.handleBatch)))
+	router.Post("/api/tasks/shift", s.withJWTAuth(lib.MakeHTTP(s.handleShiftTasks)))
+	router.Get("/api/tasks/completed", s.withJWTAuth(lib.MakeHTTP(s.handleGetCompletedTasks)))
 	router.Get("/api/nextdate", lib.MakeHTTP(s.handleNextDate))
+	router.Get("/api/projects", s.withJWTAuth(lib.MakeHTTP(s.handleGetProjects)))
+	router.HandleFunc("/api/project", s.withJWTAuth(lib.MakeHTTP(s.handleProject)))
+	router.HandleFunc("/api/project/members", s.withJWTAuth(lib.MakeHTTP(s.handleMembers)))
+	router.Get("/api/fields", s.withJWTAuth(lib.MakeHTTP(s.handleGetFields)))
+	router.HandleFunc("/api/field", s.withJWTAuth(lib.MakeHTTP(s.handleField)))
+	router.Get("/api/tags", s.withJWTAuth(lib.MakeHTTP(s.handleGetTags)))
+	router.Get("/api/audit", s.withJWTAuth(lib.MakeHTTP(s.handleGetAudit)))
+	router.Get("/api/trash", s.withJWTAuth(lib.MakeHTTP(s.handleGetTrash)))
+	router.Post("/api/trash/restore", s.withJWTAuth(lib.MakeHTTP(s.handleRestoreTask)))
+	router.Get("/api/users", s.withJWTAuth(lib.MakeHTTP(s.handleGetUsers)))
+	router.HandleFunc("/api/user", s.withJWTAuth(lib.MakeHTTP(s.handleUser)))
+	router.Get("/api/admin/backup", s.withJWTAuth(lib.MakeHTTP(s.handleBackup)))
+	router.Post("/api/admin/restore", s.withJWTAuth(lib.MakeHTTP(s.handleRestore)))
+
+	if err := os.MkdirAll(s.attachments.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create attachments dir")
+	}
+
+	go s.purgeTrash(context.Background())
 
 	log.Printf("Starting server on port %s", s.port)
 
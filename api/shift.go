@@ -0,0 +1,26 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/zeze322/todo/db"
+	"github.com/zeze322/todo/lib"
+)
+
+// handleShiftTasks reschedules the tasks of a date range after time off,
+// see db.TaskShift. With dry_run set it only previews the new dates.
+func (s *Server) handleShiftTasks(w http.ResponseWriter, r *http.Request) error {
+	var req db.TaskShift
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	tasks, err := s.store.ShiftTasks(r.Context(), req)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, db.ShiftResponse{Tasks: tasks, DryRun: req.DryRun})
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/zeze322/todo/db"
+	"github.com/zeze322/todo/lib"
+)
+
+const purgeInterval = time.Hour
+
+func (s *Server) handleGetTrash(w http.ResponseWriter, r *http.Request) error {
+	tasks, err := s.store.GetTrash(r.Context())
+	if err != nil {
+		return fmt.Errorf("failed to get trash")
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, db.TasksResponse{Tasks: tasks})
+}
+
+func (s *Server) handleRestoreTask(w http.ResponseWriter, r *http.Request) error {
+	id := r.FormValue("id")
+	if id == "" {
+		return fmt.Errorf("id not specified")
+	}
+
+	if err := s.store.RestoreTask(r.Context(), id); err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, lib.EmptyJSON{})
+}
+
+// purgeTrash removes tasks that stayed in the trash longer than the
+// retention period, once at start and then every purgeInterval. Files
+// attached to the purged tasks are removed as well.
+func (s *Server) purgeTrash(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := s.store.PurgeTrash(ctx, time.Now().Add(-s.trashRetention))
+		if err != nil {
+			log.Println("failed to purge trash", err)
+		} else if n > 0 {
+			log.Printf("Purged %d tasks from trash", n)
+		}
+
+		s.removeOrphanAttachments(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/zeze322/todo/db"
+	"github.com/zeze322/todo/lib"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type userRequest struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Admin    bool   `json:"admin"`
+}
+
+// handleUser registers, changes the password of and removes accounts.
+// Registration is closed, only admins manage users.
+func (s *Server) handleUser(w http.ResponseWriter, r *http.Request) error {
+	if !db.UserFrom(r.Context()).Admin {
+		return errForbidden("admin")
+	}
+
+	switch r.Method {
+	case "POST":
+		return s.handleCreateUser(w, r)
+	case "PUT":
+		return s.handleUpdateUser(w, r)
+	case "DELETE":
+		return s.handleDeleteUser(w, r)
+	}
+
+	return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+func (s *Server) handleGetUsers(w http.ResponseWriter, r *http.Request) error {
+	if !db.UserFrom(r.Context()).Admin {
+		return errForbidden("admin")
+	}
+
+	users, err := s.store.GetUsers(r.Context())
+	if err != nil {
+		return fmt.Errorf("failed to get users")
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, db.UsersResponse{Users: users})
+}
+
+func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) error {
+	var req userRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	user, err := db.NewUser(req.Name, req.Admin)
+	if err != nil {
+		return err
+	}
+
+	if user.PasswordHash, err = hashPassword(req.Password); err != nil {
+		return err
+	}
+
+	id, err := s.store.CreateUser(r.Context(), user)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, db.CreateTaskResponse{ID: id})
+}
+
+// handleUpdateUser sets a new password, the built-in admin keeps using
+// TODO_PASSWORD.
+func (s *Server) handleUpdateUser(w http.ResponseWriter, r *http.Request) error {
+	var req userRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	if req.ID == "" {
+		return fmt.Errorf("id not specified")
+	}
+
+	passwordHash, err := hashPassword(req.Password)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.UpdateUserPassword(r.Context(), req.ID, passwordHash); err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, lib.EmptyJSON{})
+}
+
+// handleDeleteUser removes an account together with its tasks and projects.
+func (s *Server) handleDeleteUser(w http.ResponseWriter, r *http.Request) error {
+	id := r.FormValue("id")
+	if id == "" {
+		return fmt.Errorf("id not specified")
+	}
+
+	if err := s.store.DeleteUser(r.Context(), id); err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, lib.EmptyJSON{})
+}
+
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", fmt.Errorf("password should not be empty")
+	}
+
+	b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("invalid password")
+	}
+
+	return string(b), nil
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/zeze322/todo/api"
@@ -20,15 +24,86 @@ func main() {
 		storagePath = os.Getenv("TODO_DBFILE")
 	)
 
+	retentionDays, err := strconv.Atoi(os.Getenv("TODO_TRASH_RETENTION_DAYS"))
+	if err != nil || retentionDays < 1 {
+		retentionDays = 30
+	}
+
+	maxAttachmentMB, err := strconv.Atoi(os.Getenv("TODO_ATTACHMENT_MAX_MB"))
+	if err != nil || maxAttachmentMB < 1 {
+		maxAttachmentMB = 10
+	}
+
+	attachments := api.AttachmentConfig{
+		Dir:     os.Getenv("TODO_ATTACHMENTS_DIR"),
+		MaxSize: int64(maxAttachmentMB) << 20,
+	}
+
+	if attachments.Dir == "" {
+		attachments.Dir = "./attachments"
+	}
+
 	store, err := db.NewStorage(storagePath)
 	if err != nil {
 		log.Println("db error", err)
 	}
 
+	if len(os.Args) > 1 {
+		if err == nil {
+			err = runCommand(store, os.Args[1:])
+			store.Close()
+		}
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
 	defer store.Close()
 
-	s := api.NewServer(port, password, store)
+	s := api.NewServer(port, password, time.Duration(retentionDays)*24*time.Hour, attachments, store)
 	if err := s.Run(); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// runCommand runs a subcommand instead of the server:
+//
+//	backup FILE   writes a snapshot of the database to FILE
+//	restore FILE  replaces the database with the backup in FILE
+func runCommand(store db.Storage, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: %s backup|restore FILE", os.Args[0])
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "backup":
+		f, err := os.Create(args[1])
+		if err != nil {
+			return err
+		}
+
+		if err := store.Backup(ctx, f); err != nil {
+			f.Close()
+			os.Remove(args[1])
+			return err
+		}
+
+		return f.Close()
+	case "restore":
+		f, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		return store.Restore(ctx, f)
+	}
+
+	return fmt.Errorf("unknown command %s", args[0])
+}
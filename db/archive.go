@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GetCompletedTasks returns tasks completed in [from, to), the most
+// recently completed first. Zero times leave the range open.
+func (s *SqliteStorage) GetCompletedTasks(ctx context.Context, from, to time.Time) ([]Task, error) {
+	query := `SELECT ` + taskColumns + `, '' FROM scheduler s
+		WHERE s.completed_at IS NOT NULL AND s.deleted_at IS NULL AND s.project_id IN ` + visibleProjects("?")
+
+	args := []any{userID(ctx), userID(ctx)}
+
+	if !from.IsZero() {
+		query += ` AND s.completed_at >= ?`
+		args = append(args, from.Unix())
+	}
+
+	if !to.IsZero() {
+		query += ` AND s.completed_at < ?`
+		args = append(args, to.Unix())
+	}
+
+	query += ` ORDER BY s.completed_at DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTasks(rows)
+}
+
+// UncompleteTask puts an archived task back on the list.
+func (s *SqliteStorage) UncompleteTask(ctx context.Context, id string) error {
+	_, err := s.withAudit(ctx, auditUncomplete, id, func(tx *sql.Tx) (string, error) {
+		query := `UPDATE scheduler SET completed_at=NULL WHERE id=$1 AND completed_at IS NOT NULL AND deleted_at IS NULL`
+
+		res, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return "", fmt.Errorf("failed to uncomplete task")
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return "", err
+		}
+
+		if rows == 0 {
+			return "", fmt.Errorf("completed task not found id: %s", id)
+		}
+
+		return id, nil
+	})
+
+	return err
+}
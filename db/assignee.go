@@ -0,0 +1,36 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// setAssignee assigns a task to a user with access to its project, an
+// empty id unassigns it. The assigner is only recorded when the assignee
+// changes, so saving a task unchanged keeps who assigned it.
+func setAssignee(ctx context.Context, tx *sql.Tx, taskID, assignee string) error {
+	if assignee != "" {
+		query := `SELECT 1 FROM scheduler s JOIN projects p ON p.id = s.project_id
+			WHERE s.id=$1 AND (p.user_id=$2 OR EXISTS (SELECT 1 FROM project_members m WHERE m.project_id = p.id AND m.user_id=$2))`
+
+		var exists int
+
+		err := tx.QueryRowContext(ctx, query, taskID, assignee).Scan(&exists)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("user id: %s has no access to the task", assignee)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	query := `UPDATE scheduler SET assignee_id=NULLIF($1, ''),
+		assigned_by=IIF($1 = '', NULL, IIF(assignee_id IS $1, assigned_by, $2)) WHERE id=$3`
+
+	if _, err := tx.ExecContext(ctx, query, assignee, userID(ctx), taskID); err != nil {
+		return fmt.Errorf("failed to set assignee")
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Attachment is the metadata of a file attached to a task. The file
+// itself is kept outside the database, named after the attachment id.
+type Attachment struct {
+	ID        string `json:"id"`
+	TaskID    string `json:"task_id"`
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	MimeType  string `json:"mime_type"`
+	Checksum  string `json:"checksum"`
+	CreatedAt string `json:"created_at"`
+}
+
+type AttachmentsResponse struct {
+	Attachments []Attachment `json:"attachments"`
+}
+
+const attachmentColumns = `id, task_id, name, size, mime_type, checksum, created_at`
+
+func scanAttachment(row scanner) (Attachment, error) {
+	var (
+		a         Attachment
+		createdAt sql.NullInt64
+	)
+
+	if err := row.Scan(&a.ID, &a.TaskID, &a.Name, &a.Size, &a.MimeType, &a.Checksum, &createdAt); err != nil {
+		return Attachment{}, err
+	}
+
+	a.CreatedAt = formatUnix(createdAt)
+
+	return a, nil
+}
+
+// CreateAttachment stores the metadata of a file attached to a task that
+// is not in the trash and returns the new attachment id.
+func (s *SqliteStorage) CreateAttachment(ctx context.Context, a Attachment) (string, error) {
+	query := `INSERT INTO attachments (task_id, name, size, mime_type, checksum, created_at)
+		SELECT id, $1, $2, $3, $4, $5 FROM scheduler WHERE id=$6 AND deleted_at IS NULL AND project_id IN ` + visibleProjects("$7")
+
+	res, err := s.db.ExecContext(ctx, query, a.Name, a.Size, a.MimeType, a.Checksum, time.Now().Unix(), a.TaskID, userID(ctx))
+	if err != nil {
+		return "", fmt.Errorf("failed to create attachment")
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+
+	if rows == 0 {
+		return "", fmt.Errorf("task not found id: %s", a.TaskID)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.Itoa(int(id)), nil
+}
+
+// GetAttachments lists the attachments of a task, the oldest first.
+func (s *SqliteStorage) GetAttachments(ctx context.Context, taskID string) ([]Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE task_id=$1 AND ` + userTask + ` ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, taskID, userID(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	attachments := []Attachment{}
+
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func (s *SqliteStorage) GetAttachment(ctx context.Context, id string) (Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id=$1 AND ` + userTask
+
+	a, err := scanAttachment(s.db.QueryRowContext(ctx, query, id, userID(ctx)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Attachment{}, fmt.Errorf("attachment not found id: %s", id)
+	} else if err != nil {
+		return Attachment{}, err
+	}
+
+	return a, nil
+}
+
+func (s *SqliteStorage) DeleteAttachment(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM attachments WHERE id=$1 AND `+userTask, id, userID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment")
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("attachment not found id: %s", id)
+	}
+
+	return nil
+}
+
+// GetAttachmentIDs returns the ids of all stored attachments, it is used
+// to find files left behind by purged tasks.
+func (s *SqliteStorage) GetAttachmentIDs(ctx context.Context) (map[string]bool, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id FROM attachments`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := map[string]bool{}
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+
+	return ids, rows.Err()
+}
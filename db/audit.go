@@ -0,0 +1,258 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	auditCreate     = "create"
+	auditUpdate     = "update"
+	auditDelete     = "delete"
+	auditRestore    = "restore"
+	auditComplete   = "complete"
+	auditUncomplete = "uncomplete"
+	auditUndo       = "undo"
+	auditMove       = "move"
+)
+
+const anonymous = "anonymous"
+
+type AuditEntry struct {
+	ID        string `json:"id"`
+	TaskID    string `json:"task_id"`
+	Action    string `json:"action"`
+	Field     string `json:"field"`
+	OldValue  string `json:"old_value"`
+	NewValue  string `json:"new_value"`
+	Actor     string `json:"actor"`
+	ChangedAt string `json:"changed_at"`
+}
+
+type AuditResponse struct {
+	Entries []AuditEntry `json:"entries"`
+}
+
+// AuditFilter selects audit entries. From and To limit the change time
+// to [From, To), zero values leave the range open.
+type AuditFilter struct {
+	TaskID string
+	From   time.Time
+	To     time.Time
+	// Limit defaults to 100 entries and is capped at maxLimit.
+	Limit int
+}
+
+// actorFrom returns the identity recorded in the audit log, the name of
+// the user set by WithUser.
+func actorFrom(ctx context.Context) string {
+	if user, ok := ctx.Value(userKey{}).(User); ok && user.Name != "" {
+		return user.Name
+	}
+
+	return anonymous
+}
+
+// auditFields lists the task fields tracked by the audit log.
+func auditFields(task Task) [][2]string {
+	return [][2]string{
+		{"date", task.Date},
+		{"title", task.Title},
+		{"comment", task.Comment},
+		{"repeat", task.Repeat},
+		{"tags", strings.Join(task.Tags, ",")},
+		{"project_id", task.ProjectID},
+		{"priority", task.Priority},
+		{"parent_id", task.ParentID},
+		{"auto_complete", autoComplete(task)},
+		{"blocked_by", strings.Join(task.BlockedBy, ",")},
+		{"fields", fieldsValue(task)},
+		{"deadline", optional(task.Deadline)},
+		{"defer_until", optional(task.DeferUntil)},
+		{"assignee", optional(task.Assignee)},
+		{"deleted_at", task.DeletedAt},
+		{"completed_at", task.CompletedAt},
+	}
+}
+
+func autoComplete(task Task) string {
+	if task.AutoComplete != nil && *task.AutoComplete {
+		return "true"
+	}
+
+	return ""
+}
+
+func optional(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
+
+// fieldsValue encodes custom fields as JSON, keys are sorted so equal
+// values compare equal.
+func fieldsValue(task Task) string {
+	if len(task.Fields) == 0 {
+		return ""
+	}
+
+	b, _ := json.Marshal(task.Fields)
+
+	return string(b)
+}
+
+// withAudit runs fn in a transaction and appends an audit entry for every
+// task field fn changed. id is empty when fn creates the task, fn returns
+// the id of the task it changed. The resulting task is returned.
+func (s *SqliteStorage) withAudit(ctx context.Context, action, id string, fn func(tx *sql.Tx) (string, error)) (Task, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Task{}, err
+	}
+
+	defer tx.Rollback()
+
+	task, err := auditTx(ctx, tx, action, id, fn)
+	if err != nil {
+		return Task{}, err
+	}
+
+	return task, tx.Commit()
+}
+
+// auditTx is withAudit for a transaction started by the caller, so that
+// several audited changes can be committed together.
+func auditTx(ctx context.Context, tx *sql.Tx, action, id string, fn func(tx *sql.Tx) (string, error)) (Task, error) {
+	var (
+		before Task
+		err    error
+	)
+
+	if id != "" {
+		before, err = taskSnapshot(ctx, tx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return Task{}, fmt.Errorf("task not found id: %s", id)
+		} else if err != nil {
+			return Task{}, err
+		}
+	}
+
+	id, err = fn(tx)
+	if err != nil {
+		return Task{}, err
+	}
+
+	if action != auditCreate {
+		if _, err := tx.ExecContext(ctx, `UPDATE scheduler SET version = version + 1 WHERE id=$1`, id); err != nil {
+			return Task{}, err
+		}
+	}
+
+	after, err := taskSnapshot(ctx, tx, id)
+	if err != nil {
+		return Task{}, err
+	}
+
+	// entries belong to the task owner, so they outlive sharing
+	query := `INSERT INTO audit_log (task_id, action, field, old_value, new_value, actor, changed_at, user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, (SELECT user_id FROM scheduler WHERE id=$1))`
+
+	actor := actorFrom(ctx)
+	now := time.Now().Unix()
+	old := auditFields(before)
+
+	for i, field := range auditFields(after) {
+		if action != auditCreate && old[i][1] == field[1] {
+			continue
+		}
+
+		if action == auditCreate && field[1] == "" {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, query, id, action, field[0], old[i][1], field[1], actor, now); err != nil {
+			return Task{}, fmt.Errorf("failed to write audit log")
+		}
+	}
+
+	return after, nil
+}
+
+// taskSnapshot reads a task the user can see in any state, including
+// trashed and completed. Audited changes fail for other tasks here.
+func taskSnapshot(ctx context.Context, tx *sql.Tx, id string) (Task, error) {
+	query := `SELECT ` + taskColumns + `, '' FROM scheduler s WHERE s.id=$1 AND s.project_id IN ` + visibleProjects("$2")
+
+	return scanTask(tx.QueryRowContext(ctx, query, id, userID(ctx)))
+}
+
+// GetAudit returns audit entries of the tasks the user owns or can see,
+// the latest first.
+func (s *SqliteStorage) GetAudit(ctx context.Context, filter AuditFilter) ([]AuditEntry, error) {
+	query := `SELECT id, task_id, action, field, old_value, new_value, actor, changed_at FROM audit_log
+		WHERE (user_id = ? OR task_id IN (SELECT id FROM scheduler WHERE project_id IN ` + visibleProjects("?") + `))`
+
+	args := []any{userID(ctx), userID(ctx), userID(ctx)}
+
+	if filter.TaskID != "" {
+		query += ` AND task_id = ?`
+		args = append(args, filter.TaskID)
+	}
+
+	if !filter.From.IsZero() {
+		query += ` AND changed_at >= ?`
+		args = append(args, filter.From.Unix())
+	}
+
+	if !filter.To.IsZero() {
+		query += ` AND changed_at < ?`
+		args = append(args, filter.To.Unix())
+	}
+
+	l := filter.Limit
+	switch {
+	case l <= 0:
+		l = 100
+	case l > maxLimit:
+		l = maxLimit
+	}
+
+	query += ` ORDER BY changed_at DESC, id DESC LIMIT ?`
+	args = append(args, l)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+
+	for rows.Next() {
+		var (
+			e         AuditEntry
+			changedAt sql.NullInt64
+		)
+
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.Action, &e.Field, &e.OldValue, &e.NewValue, &e.Actor, &changedAt); err != nil {
+			return nil, err
+		}
+
+		e.ChangedAt = formatUnix(changedAt)
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
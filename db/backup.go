@@ -0,0 +1,308 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Backup writes a consistent snapshot of the database to w. The snapshot
+// is taken with the SQLite online backup API into a temporary file, so
+// the server keeps serving writes meanwhile and nothing is written to w
+// if it fails. Attachment files are not part of the backup.
+func (s *SqliteStorage) Backup(ctx context.Context, w io.Writer) error {
+	path, err := tempDatabase()
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(path)
+
+	dest, err := sql.Open("sqlite3", "file:"+path)
+	if err != nil {
+		return err
+	}
+
+	defer dest.Close()
+
+	if err := copyDatabase(ctx, dest, s.db); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	if err := dest.Close(); err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+
+	return err
+}
+
+// Restore replaces the database with a backup read from r. The backup is
+// checked, pruned of broken references and migrated to the current
+// schema in a temporary file first
+// and then copied over the database in one step, readers see either the
+// old data or the restored one.
+func (s *SqliteStorage) Restore(ctx context.Context, r io.Reader) error {
+	path, err := tempDatabase()
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(path)
+
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	src, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=on&_txlock=immediate")
+	if err != nil {
+		return err
+	}
+
+	defer src.Close()
+
+	if err := checkBackup(ctx, src); err != nil {
+		return err
+	}
+
+	if err := pruneOrphans(ctx, src); err != nil {
+		return err
+	}
+
+	if err := migrate(ctx, src); err != nil {
+		return err
+	}
+
+	if err := copyDatabase(ctx, s.db, src); err != nil {
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+
+	return nil
+}
+
+func tempDatabase() (string, error) {
+	f, err := os.CreateTemp("", "todo-backup-*.db")
+	if err != nil {
+		return "", err
+	}
+
+	return f.Name(), f.Close()
+}
+
+// checkBackup makes sure a backup is an intact database of this app with
+// a schema no newer than the current one.
+func checkBackup(ctx context.Context, db *sql.DB) error {
+	var result string
+
+	if err := db.QueryRowContext(ctx, `PRAGMA integrity_check`).Scan(&result); err != nil {
+		return fmt.Errorf("backup is not a database")
+	}
+
+	if result != "ok" {
+		return fmt.Errorf("backup is corrupted: %s", result)
+	}
+
+	var tables int
+
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='scheduler'`).Scan(&tables)
+	if err != nil {
+		return err
+	}
+
+	if tables == 0 {
+		return fmt.Errorf("backup has no scheduler table")
+	}
+
+	var version int
+
+	if err := db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+
+	if version > len(migrations) {
+		return fmt.Errorf("backup schema version %d is newer than %d", version, len(migrations))
+	}
+
+	return nil
+}
+
+// pruneOrphans deletes rows referencing rows that are gone, e.g. task
+// history left by a connection that deleted tasks with foreign keys off.
+// Deleting an orphan can orphan others, so it repeats until none is left.
+func pruneOrphans(ctx context.Context, db *sql.DB) error {
+	for {
+		deletes, err := orphanDeletes(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		if len(deletes) == 0 {
+			return nil
+		}
+
+		var pruned int64
+
+		for _, query := range deletes {
+			res, err := db.ExecContext(ctx, query)
+			if err != nil {
+				return fmt.Errorf("failed to prune broken references")
+			}
+
+			rows, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+
+			pruned += rows
+		}
+
+		if pruned == 0 {
+			return fmt.Errorf("backup has broken references")
+		}
+	}
+}
+
+// orphanDeletes builds a DELETE for every foreign key that has broken
+// references, it keeps rows with a NULL reference as SQLite does.
+func orphanDeletes(ctx context.Context, db *sql.DB) ([]string, error) {
+	type foreignKey struct {
+		table string
+		id    int
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT DISTINCT "table", fkid FROM pragma_foreign_key_check`)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []foreignKey
+
+	for rows.Next() {
+		var k foreignKey
+		if err := rows.Scan(&k.table, &k.id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var deletes []string
+
+	for _, k := range keys {
+		rows, err := db.QueryContext(ctx, `SELECT "table", "from", IFNULL("to", 'rowid') FROM pragma_foreign_key_list($1) WHERE id=$2`, k.table, k.id)
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			parent string
+			set    []string
+			match  []string
+		)
+
+		for rows.Next() {
+			var from, to string
+			if err := rows.Scan(&parent, &from, &to); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			set = append(set, "c."+quoteIdent(from)+" IS NOT NULL")
+			match = append(match, "p."+quoteIdent(to)+" = c."+quoteIdent(from))
+		}
+
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		if len(match) == 0 {
+			continue
+		}
+
+		deletes = append(deletes, `DELETE FROM `+quoteIdent(k.table)+` AS c WHERE `+strings.Join(set, " AND ")+
+			` AND NOT EXISTS (SELECT 1 FROM `+quoteIdent(parent)+` AS p WHERE `+strings.Join(match, " AND ")+`)`)
+	}
+
+	return deletes, nil
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// copyDatabase copies src over dest with the SQLite online backup API in
+// a single step, retrying while another connection holds a lock.
+func copyDatabase(ctx context.Context, dest, src *sql.DB) error {
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer destConn.Close()
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer srcConn.Close()
+
+	return destConn.Raw(func(d any) error {
+		return srcConn.Raw(func(s any) error {
+			backup, err := d.(*sqlite3.SQLiteConn).Backup("main", s.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+
+			for {
+				done, err := backup.Step(-1)
+				if err != nil {
+					backup.Finish()
+					return err
+				}
+
+				if done {
+					return backup.Finish()
+				}
+
+				select {
+				case <-ctx.Done():
+					backup.Finish()
+					return ctx.Err()
+				case <-time.After(50 * time.Millisecond):
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+const maxBatchSize = 500
+
+type BatchMode string
+
+const (
+	// BatchAtomic applies all operations of a batch or none of them.
+	BatchAtomic BatchMode = "atomic"
+	// BatchBestEffort applies the operations that succeed and reports the
+	// failed ones.
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+type BatchAction string
+
+const (
+	BatchCreate   BatchAction = "create"
+	BatchUpdate   BatchAction = "update"
+	BatchDelete   BatchAction = "delete"
+	BatchComplete BatchAction = "complete"
+	BatchMove     BatchAction = "move"
+)
+
+// BatchOp is one operation of a batch. Task holds the fields of a created
+// or updated task, ProjectID the target of a move and Note the note of a
+// completion. A non-zero Version must match the version of the task.
+type BatchOp struct {
+	Action    BatchAction
+	ID        string
+	Task      Task
+	ProjectID string
+	Note      string
+	Version   int
+	// Err marks an operation rejected before the batch runs, it fails
+	// without touching the database.
+	Err error
+}
+
+type BatchStatus string
+
+const (
+	BatchDone       BatchStatus = "done"
+	BatchFailed     BatchStatus = "failed"
+	BatchRolledBack BatchStatus = "rolled_back"
+	BatchSkipped    BatchStatus = "skipped"
+)
+
+// BatchResult reports the outcome of an operation, ID is the id of the
+// created task for create operations.
+type BatchResult struct {
+	Action BatchAction `json:"op"`
+	ID     string      `json:"id,omitempty"`
+	Status BatchStatus `json:"status"`
+	Error  string      `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// Batch runs task operations in one transaction. In atomic mode the first
+// failure rolls back the whole batch and is returned along with the
+// results, in best-effort mode every operation runs in its own savepoint
+// so a failed one is undone alone.
+func (s *SqliteStorage) Batch(ctx context.Context, ops []BatchOp, mode BatchMode) ([]BatchResult, error) {
+	if mode != BatchAtomic && mode != BatchBestEffort {
+		return nil, fmt.Errorf("unknown batch mode %s", mode)
+	}
+
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("batch has no operations")
+	}
+
+	if len(ops) > maxBatchSize {
+		return nil, fmt.Errorf("batch has more than %d operations", maxBatchSize)
+	}
+
+	results := make([]BatchResult, len(ops))
+	for i, op := range ops {
+		results[i] = BatchResult{Action: op.Action, ID: op.ID, Status: BatchSkipped}
+	}
+
+	if mode == BatchAtomic {
+		for i, op := range ops {
+			if op.Err != nil {
+				return results, batchFailed(results, i, op.Err)
+			}
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	for i, op := range ops {
+		if op.Err != nil {
+			results[i].Status, results[i].Error = BatchFailed, op.Err.Error()
+			continue
+		}
+
+		if mode == BatchAtomic {
+			id, err := runBatchOp(ctx, tx, op)
+			if err != nil {
+				return results, batchFailed(results, i, err)
+			}
+
+			results[i].ID, results[i].Status = id, BatchDone
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT batch_op`); err != nil {
+			return nil, err
+		}
+
+		id, err := runBatchOp(ctx, tx, op)
+		if err != nil {
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO batch_op`); err != nil {
+				return nil, err
+			}
+			results[i].Status, results[i].Error = BatchFailed, err.Error()
+		} else {
+			results[i].ID, results[i].Status = id, BatchDone
+		}
+
+		if _, err := tx.ExecContext(ctx, `RELEASE batch_op`); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, tx.Commit()
+}
+
+// batchFailed marks the operation at i as failed and the ones applied
+// before it as rolled back.
+func batchFailed(results []BatchResult, i int, err error) error {
+	for j := range results[:i] {
+		results[j].Status = BatchRolledBack
+	}
+
+	results[i].Status, results[i].Error = BatchFailed, err.Error()
+
+	return fmt.Errorf("operation %d failed: %w", i+1, err)
+}
+
+func runBatchOp(ctx context.Context, tx *sql.Tx, op BatchOp) (string, error) {
+	switch op.Action {
+	case BatchCreate:
+		created, err := auditTx(ctx, tx, auditCreate, "", func(tx *sql.Tx) (string, error) {
+			return insertTask(ctx, tx, op.Task)
+		})
+		return created.ID, err
+	case BatchUpdate:
+		op.Task.Version = op.Version
+		_, err := auditTx(ctx, tx, auditUpdate, op.ID, func(tx *sql.Tx) (string, error) {
+			return updateTask(ctx, tx, op.ID, op.Task)
+		})
+		return op.ID, err
+	case BatchDelete:
+		if err := checkVersion(ctx, tx, op.ID, op.Version); err != nil {
+			return "", err
+		}
+		_, err := auditTx(ctx, tx, auditDelete, op.ID, func(tx *sql.Tx) (string, error) {
+			return trashTask(ctx, tx, op.ID)
+		})
+		return op.ID, err
+	case BatchComplete:
+		_, err := completeTask(ctx, tx, op.ID, op.Note, op.Version)
+		return op.ID, err
+	case BatchMove:
+		return op.ID, moveTask(ctx, tx, op.ID, op.ProjectID, op.Version)
+	}
+
+	return "", fmt.Errorf("unknown operation %s", op.Action)
+}
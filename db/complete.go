@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zeze322/todo/lib"
+	"github.com/zeze322/todo/repeattask"
+)
+
+// CompleteTask marks one occurrence of a task as done: the completion is
+// recorded, a one-off task goes to the archive and a repeating one moves
+// to its next date with its checklist unchecked. The task is read and
+// changed in one transaction, so two quick completions can't skip an
+// occurrence. Blocked tasks can't be completed, a non-zero version must
+// match the version of the task. Completing the last open subtask of a
+// parent with auto_complete set completes the parent as well. The
+// resulting task is returned.
+func (s *SqliteStorage) CompleteTask(ctx context.Context, id, note string, version int) (Task, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Task{}, err
+	}
+
+	defer tx.Rollback()
+
+	task, err := completeTask(ctx, tx, id, note, version)
+	if err != nil {
+		return Task{}, err
+	}
+
+	return task, tx.Commit()
+}
+
+// CanComplete reports whether the user may mark a task as done. Anyone
+// who can edit an unassigned task can, an assigned one is left to its
+// assignee and the user who assigned it.
+func CanComplete(ctx context.Context, task Task) bool {
+	if task.Assignee == nil {
+		return true
+	}
+
+	user := userID(ctx)
+
+	return *task.Assignee == user || task.AssignedBy == user
+}
+
+func completeTask(ctx context.Context, tx *sql.Tx, id, note string, version int) (Task, error) {
+	task, err := taskSnapshot(ctx, tx, id)
+	if errors.Is(err, sql.ErrNoRows) || err == nil && (task.DeletedAt != "" || task.CompletedAt != "") {
+		return Task{}, fmt.Errorf("task not found id: %s", id)
+	} else if err != nil {
+		return Task{}, err
+	}
+
+	if version != 0 && task.Version != version {
+		return Task{}, &VersionConflictError{ID: id, Version: task.Version}
+	}
+
+	if task.Blocked {
+		return Task{}, fmt.Errorf("task is blocked by unfinished tasks: %s", strings.Join(task.BlockedBy, ", "))
+	}
+
+	if err := recordCompletion(ctx, tx, Completion{TaskID: id, ScheduledDate: task.Date, Note: note}); err != nil {
+		return Task{}, err
+	}
+
+	if task.Repeat == "" {
+		task, err = auditTx(ctx, tx, auditComplete, id, func(tx *sql.Tx) (string, error) {
+			if _, err := tx.ExecContext(ctx, `UPDATE scheduler SET completed_at=$1 WHERE id=$2`, time.Now().Unix(), id); err != nil {
+				return "", fmt.Errorf("failed to complete task")
+			}
+
+			return id, nil
+		})
+		if err != nil {
+			return Task{}, err
+		}
+
+		return task, completeParent(ctx, tx, task.ParentID)
+	}
+
+	// the same rules as a shift, counted from today
+	today, _ := time.Parse(lib.Layout, time.Now().Format(lib.Layout))
+
+	next, err := repeattask.NextDate(today, task.Date, task.Repeat)
+	if err != nil {
+		return Task{}, err
+	}
+
+	if err := rescheduleTask(ctx, tx, task, next); err != nil {
+		return Task{}, err
+	}
+
+	if err := resetSubtasks(ctx, tx, id); err != nil {
+		return Task{}, err
+	}
+
+	return taskSnapshot(ctx, tx, id)
+}
+
+// completeParent completes a parent with auto_complete set once all of
+// its subtasks are done, an assigned parent only when the user could
+// complete it by hand.
+func completeParent(ctx context.Context, tx *sql.Tx, parentID string) error {
+	if parentID == "" {
+		return nil
+	}
+
+	parent, err := taskSnapshot(ctx, tx, parentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// the parent is already completed or trashed
+	if parent.DeletedAt != "" || parent.CompletedAt != "" {
+		return nil
+	}
+
+	if parent.AutoComplete == nil || !*parent.AutoComplete || parent.Blocked || parent.Progress == nil || parent.Progress.Done < parent.Progress.Total {
+		return nil
+	}
+
+	if !CanComplete(ctx, parent) {
+		return nil
+	}
+
+	_, err = completeTask(ctx, tx, parentID, "", 0)
+
+	return err
+}
+
+// rescheduleTask moves a task to another date, its deadline and defer
+// date keep their distance from the task date.
+func rescheduleTask(ctx context.Context, tx *sql.Tx, task Task, date string) error {
+	offset := daysBetween(task.Date, date)
+
+	_, err := auditTx(ctx, tx, auditUpdate, task.ID, func(tx *sql.Tx) (string, error) {
+		query := `UPDATE scheduler SET date=$1, deadline=$2, defer_until=$3 WHERE id=$4`
+
+		if _, err := tx.ExecContext(ctx, query, date, shiftOptional(task.Deadline, offset), shiftOptional(task.DeferUntil, offset), task.ID); err != nil {
+			return "", fmt.Errorf("failed to reschedule task id: %s", task.ID)
+		}
+
+		return task.ID, nil
+	})
+
+	return err
+}
+
+func shiftOptional(date *string, days int) *string {
+	if date == nil {
+		return nil
+	}
+
+	t, _ := time.Parse(lib.Layout, *date)
+	shifted := t.AddDate(0, 0, days).Format(lib.Layout)
+
+	return &shifted
+}
+
+func daysBetween(from, to string) int {
+	f, _ := time.Parse(lib.Layout, from)
+	t, _ := time.Parse(lib.Layout, to)
+	return int(t.Sub(f).Hours() / 24)
+}
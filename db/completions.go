@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+func recordCompletion(ctx context.Context, tx *sql.Tx, c Completion) error {
+	query := `INSERT INTO completions (task_id, scheduled_date, completed_at, note) VALUES ($1, $2, $3, $4)`
+
+	if _, err := tx.ExecContext(ctx, query, c.TaskID, c.ScheduledDate, time.Now().Unix(), c.Note); err != nil {
+		return fmt.Errorf("failed to record completion")
+	}
+
+	return nil
+}
+
+// GetCompletions returns the completion history of a task, the latest first.
+func (s *SqliteStorage) GetCompletions(ctx context.Context, taskID string) ([]Completion, error) {
+	query := `SELECT id, task_id, scheduled_date, completed_at, note FROM completions
+		WHERE task_id=$1 AND ` + userTask + ` ORDER BY completed_at DESC, id DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, taskID, userID(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	completions := []Completion{}
+
+	for rows.Next() {
+		var (
+			c           Completion
+			completedAt sql.NullInt64
+		)
+
+		if err := rows.Scan(&c.ID, &c.TaskID, &c.ScheduledDate, &completedAt, &c.Note); err != nil {
+			return nil, err
+		}
+
+		c.CompletedAt = formatUnix(completedAt)
+		completions = append(completions, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return completions, nil
+}
+
+// UndoCompletion reverts the last completion of a task: the task gets
+// back the date it was scheduled for and leaves the archive if it was a
+// one-off task.
+func (s *SqliteStorage) UndoCompletion(ctx context.Context, taskID string) (Task, error) {
+	return s.withAudit(ctx, auditUndo, taskID, func(tx *sql.Tx) (string, error) {
+		var (
+			completionID  string
+			scheduledDate string
+			date          string
+			deadline      *string
+			deferUntil    *string
+		)
+
+		query := `SELECT c.id, c.scheduled_date, s.date, s.deadline, s.defer_until FROM completions c
+			JOIN scheduler s ON s.id = c.task_id
+			WHERE c.task_id=$1 AND s.deleted_at IS NULL
+			ORDER BY c.completed_at DESC, c.id DESC LIMIT 1`
+
+		err := tx.QueryRowContext(ctx, query, taskID).Scan(&completionID, &scheduledDate, &date, &deadline, &deferUntil)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("no completion to undo for task id: %s", taskID)
+		} else if err != nil {
+			return "", err
+		}
+
+		// the deadline and defer date went forward together with the date,
+		// take them back as well
+		offset := daysBetween(date, scheduledDate)
+
+		query = `UPDATE scheduler SET date=$1, deadline=$2, defer_until=$3, completed_at=NULL WHERE id=$4`
+
+		if _, err := tx.ExecContext(ctx, query, scheduledDate, shiftOptional(deadline, offset), shiftOptional(deferUntil, offset), taskID); err != nil {
+			return "", fmt.Errorf("failed to undo completion")
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM completions WHERE id=$1`, completionID); err != nil {
+			return "", fmt.Errorf("failed to undo completion")
+		}
+
+		return taskID, nil
+	})
+}
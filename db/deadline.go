@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// checkDeadline makes sure the deadline a task keeps on update is not
+// before its new date.
+func checkDeadline(ctx context.Context, tx *sql.Tx, id, date string) error {
+	var deadline sql.NullString
+
+	err := tx.QueryRowContext(ctx, `SELECT deadline FROM scheduler WHERE id=$1`, id).Scan(&deadline)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if deadline.Valid && deadline.String < date {
+		return fmt.Errorf("deadline can't be before the task date")
+	}
+
+	return nil
+}
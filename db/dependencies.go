@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+type BlockedFilter string
+
+const (
+	BlockedAny  BlockedFilter = ""
+	BlockedOnly BlockedFilter = "blocked"
+	BlockedNone BlockedFilter = "unblocked"
+)
+
+// blockedTask matches tasks with at least one blocker that is neither
+// completed nor in the trash. Repeating blockers never complete, so they
+// block for as long as the dependency exists.
+const blockedTask = `EXISTS (SELECT 1 FROM task_dependencies d JOIN scheduler b ON b.id = d.blocker_id
+	WHERE d.task_id = s.id AND b.deleted_at IS NULL AND b.completed_at IS NULL)`
+
+// NormalizeBlockers checks that blocker ids are numbers, drops duplicates
+// and sorts them. A nil slice stays nil, it means "leave the dependencies
+// as they are" on update.
+func NormalizeBlockers(ids []string) ([]string, error) {
+	if ids == nil {
+		return nil, nil
+	}
+
+	seen := make(map[int]bool, len(ids))
+	numbers := make([]int, 0, len(ids))
+
+	for _, id := range ids {
+		n, err := strconv.Atoi(id)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid blocker id %s", id)
+		}
+
+		if !seen[n] {
+			seen[n] = true
+			numbers = append(numbers, n)
+		}
+	}
+
+	sort.Ints(numbers)
+
+	normalized := make([]string, len(numbers))
+	for i, n := range numbers {
+		normalized[i] = strconv.Itoa(n)
+	}
+
+	return normalized, nil
+}
+
+// setDependencies replaces the blockers of a task. A blocker must be a
+// task the user can see outside the trash and must not depend on the
+// task, directly or through other tasks.
+func setDependencies(ctx context.Context, tx *sql.Tx, taskID string, blockers []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM task_dependencies WHERE task_id=$1`, taskID); err != nil {
+		return fmt.Errorf("failed to set dependencies")
+	}
+
+	for _, blocker := range blockers {
+		if blocker == taskID {
+			return fmt.Errorf("task can't block itself")
+		}
+
+		var exists int
+
+		err := tx.QueryRowContext(ctx, `SELECT 1 FROM scheduler WHERE id=$1 AND deleted_at IS NULL AND project_id IN `+visibleProjects("$2"), blocker, userID(ctx)).Scan(&exists)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("blocker not found id: %s", blocker)
+		} else if err != nil {
+			return err
+		}
+
+		cycle, err := dependsOn(ctx, tx, blocker, taskID)
+		if err != nil {
+			return err
+		}
+
+		if cycle {
+			return fmt.Errorf("dependency cycle: task %s already depends on %s", blocker, taskID)
+		}
+
+		query := `INSERT INTO task_dependencies (task_id, blocker_id) VALUES ($1, $2)`
+		if _, err := tx.ExecContext(ctx, query, taskID, blocker); err != nil {
+			return fmt.Errorf("failed to set dependencies")
+		}
+	}
+
+	return nil
+}
+
+// dependsOn reports whether task is blocked by blocker through any chain
+// of dependencies.
+func dependsOn(ctx context.Context, tx *sql.Tx, task, blocker string) (bool, error) {
+	query := `WITH RECURSIVE blockers (id) AS (
+			SELECT blocker_id FROM task_dependencies WHERE task_id = $1
+			UNION
+			SELECT d.blocker_id FROM task_dependencies d JOIN blockers b ON d.task_id = b.id
+		)
+		SELECT EXISTS (SELECT 1 FROM blockers WHERE id = $2)`
+
+	var found bool
+
+	if err := tx.QueryRowContext(ctx, query, task, blocker).Scan(&found); err != nil {
+		return false, err
+	}
+
+	return found, nil
+}
@@ -0,0 +1,259 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zeze322/todo/lib"
+)
+
+const maxFieldName = 64
+
+// Types of custom field values.
+const (
+	FieldString = "string"
+	FieldNumber = "number"
+	FieldDate   = "date"
+	FieldBool   = "bool"
+)
+
+// FieldDefinition declares a custom task field. Values are stored with
+// their SQLite type: numbers as REAL, dates in lib.Layout, bools as 0 or 1.
+// Definitions are shared by all users and managed by an admin.
+type FieldDefinition struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type FieldsResponse struct {
+	Fields []FieldDefinition `json:"fields"`
+}
+
+// FieldCondition compares a custom field with a value, e.g. cost > 100.
+// Tasks without the field never match.
+type FieldCondition struct {
+	Name  string
+	Op    string
+	Value string
+}
+
+// fieldOps whitelists the comparison operators of FieldCondition.
+var fieldOps = map[string]bool{"=": true, "!=": true, ">": true, ">=": true, "<": true, "<=": true}
+
+// NewFieldDefinition checks the field name and type. Names are lowercase
+// identifiers so they can be used in search queries as field.NAME.
+func NewFieldDefinition(id, name, typ string) (FieldDefinition, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	if name == "" {
+		return FieldDefinition{}, fmt.Errorf("field name should not be empty")
+	}
+
+	if len(name) > maxFieldName || strings.IndexFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_')
+	}) >= 0 || name[0] >= '0' && name[0] <= '9' {
+		return FieldDefinition{}, fmt.Errorf("invalid field name %s", name)
+	}
+
+	switch typ {
+	case FieldString, FieldNumber, FieldDate, FieldBool:
+	default:
+		return FieldDefinition{}, fmt.Errorf("unknown field type %s", typ)
+	}
+
+	return FieldDefinition{ID: id, Name: name, Type: typ}, nil
+}
+
+func (c FieldCondition) validate() error {
+	if !fieldOps[c.Op] {
+		return fmt.Errorf("unknown operator %s", c.Op)
+	}
+
+	if c.Name == "" || c.Value == "" {
+		return fmt.Errorf("invalid field condition %s%s%s", c.Name, c.Op, c.Value)
+	}
+
+	return nil
+}
+
+// fieldValue converts a JSON value to the stored form of a field type.
+func fieldValue(typ string, v any) (any, error) {
+	switch typ {
+	case FieldString:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case FieldNumber:
+		switch n := v.(type) {
+		case float64:
+			return n, nil
+		case string:
+			if f, err := strconv.ParseFloat(n, 64); err == nil {
+				return f, nil
+			}
+		}
+	case FieldDate:
+		if s, ok := v.(string); ok {
+			if lib.IsDate(s) {
+				return lib.ParseTime(s)
+			}
+			if _, err := time.Parse(lib.Layout, s); err == nil {
+				return s, nil
+			}
+		}
+	case FieldBool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid %s value %v", typ, v)
+}
+
+// setFields replaces the custom field values of a task, a null value
+// leaves the field unset.
+func setFields(ctx context.Context, tx *sql.Tx, taskID string, fields map[string]any) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM task_fields WHERE task_id=$1`, taskID); err != nil {
+		return fmt.Errorf("failed to set fields")
+	}
+
+	for name, v := range fields {
+		if v == nil {
+			continue
+		}
+
+		var fieldID, typ string
+
+		err := tx.QueryRowContext(ctx, `SELECT id, type FROM field_definitions WHERE name=$1`, strings.ToLower(name)).Scan(&fieldID, &typ)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("unknown field %s", name)
+		} else if err != nil {
+			return err
+		}
+
+		value, err := fieldValue(typ, v)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+
+		query := `INSERT INTO task_fields (task_id, field_id, value) VALUES ($1, $2, $3)`
+		if _, err := tx.ExecContext(ctx, query, taskID, fieldID, value); err != nil {
+			return fmt.Errorf("failed to set fields")
+		}
+	}
+
+	return nil
+}
+
+// decodeFields reads the JSON object built by taskColumns.
+func decodeFields(s sql.NullString) map[string]any {
+	if !s.Valid || s.String == "{}" {
+		return nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(s.String), &fields); err != nil {
+		return nil
+	}
+
+	return fields
+}
+
+func (s *SqliteStorage) GetFields(ctx context.Context) ([]FieldDefinition, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, type FROM field_definitions ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	fields := []FieldDefinition{}
+
+	for rows.Next() {
+		var f FieldDefinition
+		if err := rows.Scan(&f.ID, &f.Name, &f.Type); err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+func (s *SqliteStorage) CreateField(ctx context.Context, f FieldDefinition) (string, error) {
+	query := `INSERT INTO field_definitions (name, type, created_at) VALUES ($1, $2, $3) ON CONFLICT (name) DO NOTHING`
+
+	res, err := s.db.ExecContext(ctx, query, f.Name, f.Type, time.Now().Unix())
+	if err != nil {
+		return "", fmt.Errorf("failed to create field")
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+
+	if rows == 0 {
+		return "", fmt.Errorf("field already exists %s", f.Name)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprint(id), nil
+}
+
+// UpdateField renames a field or changes its type, the type can only
+// change while no task has a value for the field.
+func (s *SqliteStorage) UpdateField(ctx context.Context, f FieldDefinition) error {
+	query := `UPDATE field_definitions SET name=$1, type=$2
+		WHERE id=$3 AND (type=$2 OR NOT EXISTS (SELECT 1 FROM task_fields WHERE field_id=$3))`
+
+	res, err := s.db.ExecContext(ctx, query, f.Name, f.Type, f.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update field")
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("field not found or in use with another type id: %s", f.ID)
+	}
+
+	return nil
+}
+
+// DeleteField removes a field definition together with its values.
+func (s *SqliteStorage) DeleteField(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM field_definitions WHERE id=$1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete field")
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("field not found id: %s", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,344 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zeze322/todo/lib"
+)
+
+type RepeatFilter string
+
+const (
+	RepeatAny  RepeatFilter = ""
+	RepeatOnly RepeatFilter = "repeat"
+	RepeatNone RepeatFilter = "once"
+)
+
+// SortKey orders tasks by one field, see ParseSort.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// sortColumns whitelists the fields tasks can be sorted by. relevance
+// only applies when the filter has text terms and is skipped otherwise.
+var sortColumns = map[string]string{
+	"date":      "s.date",
+	"title":     "s.title",
+	"priority":  "s.priority",
+	"deadline":  "s.deadline IS NULL, s.deadline",
+	"created":   "s.id",
+	"relevance": "bm25(scheduler_fts, 10.0, 1.0)",
+}
+
+// ParseSort parses a comma separated list of fields such as
+// "date,-priority,title", a leading minus sorts in descending order.
+func ParseSort(s string) ([]SortKey, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var (
+		keys []SortKey
+		seen = map[string]bool{}
+	)
+
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+
+		key := SortKey{Field: strings.TrimPrefix(field, "-"), Desc: strings.HasPrefix(field, "-")}
+
+		if _, ok := sortColumns[key.Field]; !ok {
+			return nil, fmt.Errorf("unknown sort field %s", field)
+		}
+
+		if seen[key.Field] {
+			return nil, fmt.Errorf("duplicate sort field %s", key.Field)
+		}
+
+		seen[key.Field] = true
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// TaskFilter describes which tasks GetTasks returns. Zero values mean
+// "no restriction", so an empty filter lists the nearest tasks by date.
+type TaskFilter struct {
+	// From and To limit the task date, both inclusive, in lib.Layout.
+	From string
+	To   string
+	// DeadlineFrom and DeadlineTo limit the deadline the same way, tasks
+	// without a deadline are left out when either is set.
+	DeadlineFrom string
+	DeadlineTo   string
+	// IncludeDeferred also lists tasks whose defer date is in the future.
+	IncludeDeferred bool
+	// Terms are full-text conditions over title and comment.
+	Terms []TextTerm
+	// Repeat restricts tasks to repeating or one-off ones.
+	Repeat RepeatFilter
+	// RepeatRule keeps repeating tasks whose rule starts with the
+	// given letter (d, w, m or y).
+	RepeatRule string
+	// ProjectID and ProjectName limit tasks to one project.
+	ProjectID   string
+	ProjectName string
+	// Tags must all be set on a task, ExcludeTags must not be set.
+	Tags        []string
+	ExcludeTags []string
+	// Priority keeps tasks with exactly this priority.
+	Priority string
+	Overdue  bool
+	// Fields are conditions on custom fields, all of them must match.
+	Fields []FieldCondition
+	// Assignee keeps tasks assigned to the user with this id.
+	Assignee string
+	// Blocked keeps tasks waiting on their blockers or ready ones.
+	Blocked BlockedFilter
+	// ParentID lists the subtasks of a task, completed ones included.
+	// Without it only top-level tasks are returned.
+	ParentID string
+	// Sort defaults to relevance for text queries and to date otherwise.
+	Sort []SortKey
+	// Limit defaults to 25 tasks and is capped at maxLimit.
+	Limit int
+}
+
+func (f TaskFilter) Validate() error {
+	for _, date := range []string{f.From, f.To, f.DeadlineFrom, f.DeadlineTo} {
+		if date == "" {
+			continue
+		}
+
+		if _, err := time.Parse(lib.Layout, date); err != nil {
+			return fmt.Errorf("invalid date %s", date)
+		}
+	}
+
+	switch f.Repeat {
+	case RepeatAny, RepeatOnly, RepeatNone:
+	default:
+		return fmt.Errorf("unknown repeat filter %s", f.Repeat)
+	}
+
+	switch f.Blocked {
+	case BlockedAny, BlockedOnly, BlockedNone:
+	default:
+		return fmt.Errorf("unknown blocked filter %s", f.Blocked)
+	}
+
+	if f.RepeatRule != "" && (len(f.RepeatRule) > 1 || !mapping[f.RepeatRule[0]]) {
+		return fmt.Errorf("unknown rule %s", f.RepeatRule)
+	}
+
+	for _, term := range f.Terms {
+		if !textFields[term.Field] {
+			return fmt.Errorf("unknown field %s", term.Field)
+		}
+	}
+
+	for _, key := range f.Sort {
+		if _, ok := sortColumns[key.Field]; !ok {
+			return fmt.Errorf("unknown sort field %s", key.Field)
+		}
+	}
+
+	if f.Priority != "" {
+		if _, err := ParsePriority(f.Priority); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range f.Fields {
+		if err := c.validate(); err != nil {
+			return err
+		}
+	}
+
+	if f.Limit < 0 {
+		return fmt.Errorf("invalid limit %d", f.Limit)
+	}
+
+	return nil
+}
+
+// query builds the SELECT statement for the filter over the tasks in the
+// projects a user can see. Tasks are read from the scheduler table
+// aliased as s; when there are positive text terms the full-text index is
+// joined to rank the results and build snippets.
+func (f TaskFilter) query(userID string) (string, []any) {
+	var (
+		where = []string{`s.project_id IN ` + visibleProjects("?"), activeTask, `s.parent_id IS NULL`}
+		args  = []any{userID, userID}
+	)
+
+	if f.ParentID != "" {
+		where = []string{`s.project_id IN ` + visibleProjects("?"), `s.deleted_at IS NULL`, `s.parent_id = ?`}
+		args = append(args, f.ParentID)
+	}
+
+	var positive, negative []TextTerm
+	for _, term := range f.Terms {
+		if term.Negate {
+			negative = append(negative, term)
+		} else {
+			positive = append(positive, term)
+		}
+	}
+
+	columns := taskColumns
+	from := `scheduler s`
+
+	if match := ftsMatch(positive); match != "" {
+		columns += `, snippet(scheduler_fts, -1, char(2), char(3), '…', 12)`
+		from = `scheduler_fts JOIN scheduler s ON s.id = scheduler_fts.rowid`
+		where = append(where, `scheduler_fts MATCH ?`)
+		args = append(args, match)
+	} else {
+		columns += `, ''`
+	}
+
+	for _, term := range negative {
+		if match := ftsMatch([]TextTerm{term}); match != "" {
+			where = append(where, `s.id NOT IN (SELECT rowid FROM scheduler_fts WHERE scheduler_fts MATCH ?)`)
+			args = append(args, match)
+		}
+	}
+
+	if f.From != "" {
+		where = append(where, `s.date >= ?`)
+		args = append(args, f.From)
+	}
+
+	if f.To != "" {
+		where = append(where, `s.date <= ?`)
+		args = append(args, f.To)
+	}
+
+	if !f.IncludeDeferred && f.ParentID == "" {
+		where = append(where, `(s.defer_until IS NULL OR s.defer_until <= ?)`)
+		args = append(args, time.Now().Format(lib.Layout))
+	}
+
+	if f.DeadlineFrom != "" {
+		where = append(where, `s.deadline >= ?`)
+		args = append(args, f.DeadlineFrom)
+	}
+
+	if f.DeadlineTo != "" {
+		where = append(where, `s.deadline <= ?`)
+		args = append(args, f.DeadlineTo)
+	}
+
+	switch f.Repeat {
+	case RepeatOnly:
+		where = append(where, `s.repeat <> ''`)
+	case RepeatNone:
+		where = append(where, `s.repeat = ''`)
+	}
+
+	switch f.Blocked {
+	case BlockedOnly:
+		where = append(where, blockedTask)
+	case BlockedNone:
+		where = append(where, `NOT `+blockedTask)
+	}
+
+	if f.RepeatRule != "" {
+		where = append(where, `s.repeat LIKE ?`)
+		args = append(args, f.RepeatRule+"%")
+	}
+
+	if f.ProjectID != "" {
+		where = append(where, `s.project_id = ?`)
+		args = append(args, f.ProjectID)
+	}
+
+	if f.ProjectName != "" {
+		where = append(where, `s.project_id IN (SELECT id FROM projects WHERE name = ? COLLATE NOCASE)`)
+		args = append(args, f.ProjectName)
+	}
+
+	if f.Assignee != "" {
+		where = append(where, `s.assignee_id = ?`)
+		args = append(args, f.Assignee)
+	}
+
+	if f.Priority != "" {
+		priority, _ := ParsePriority(f.Priority)
+		where = append(where, `s.priority = ?`)
+		args = append(args, priority)
+	}
+
+	const hasTag = `EXISTS (SELECT 1 FROM task_tags tt JOIN tags t ON t.id = tt.tag_id WHERE tt.task_id = s.id AND t.name = ?)`
+
+	for _, tag := range f.Tags {
+		where = append(where, hasTag)
+		args = append(args, tag)
+	}
+
+	for _, tag := range f.ExcludeTags {
+		where = append(where, `NOT `+hasTag)
+		args = append(args, tag)
+	}
+
+	// the value is converted to the type of the field before comparing
+	for _, c := range f.Fields {
+		where = append(where, `EXISTS (SELECT 1 FROM task_fields tf JOIN field_definitions f ON f.id = tf.field_id
+			WHERE tf.task_id = s.id AND f.name = ? AND tf.value `+c.Op+` CASE f.type
+				WHEN 'number' THEN CAST(? AS REAL)
+				WHEN 'bool' THEN ? IN ('true', '1', 'yes')
+				ELSE ? END)`)
+		args = append(args, strings.ToLower(c.Name), c.Value, strings.ToLower(c.Value), c.Value)
+	}
+
+	if f.Overdue {
+		where = append(where, `s.date < ?`)
+		args = append(args, time.Now().Format(lib.Layout))
+	}
+
+	query := `SELECT ` + columns + ` FROM ` + from + ` WHERE ` + strings.Join(where, ` AND `)
+
+	query += ` ORDER BY ` + f.orderBy(strings.HasPrefix(from, "scheduler_fts"))
+
+	l := f.Limit
+	switch {
+	case l == 0:
+		l = limit
+	case l > maxLimit:
+		l = maxLimit
+	}
+
+	query += ` LIMIT ?`
+	args = append(args, l)
+
+	return query, args
+}
+
+func (f TaskFilter) orderBy(ranked bool) string {
+	keys := f.Sort
+	if len(keys) == 0 {
+		keys = []SortKey{{Field: "relevance"}, {Field: "date"}}
+	}
+
+	var order []string
+
+	for _, key := range keys {
+		if key.Field == "relevance" && !ranked {
+			continue
+		}
+
+		column := sortColumns[key.Field]
+		if key.Desc {
+			column += " DESC"
+		}
+
+		order = append(order, column)
+	}
+
+	return strings.Join(append(order, "s.date ASC", "s.id ASC"), ", ")
+}
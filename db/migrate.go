@@ -0,0 +1,281 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrations are applied in order on top of the base scheduler table.
+// The index of the last applied migration is kept in PRAGMA user_version,
+// so new schema changes must only ever be appended to the list.
+var migrations = []string{
+	// full-text index over title and comment kept in sync by triggers
+	`
+	CREATE VIRTUAL TABLE IF NOT EXISTS scheduler_fts USING fts5(
+		title,
+		comment,
+		content='scheduler',
+		content_rowid='id',
+		tokenize='unicode61 remove_diacritics 2'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS scheduler_fts_insert AFTER INSERT ON scheduler BEGIN
+		INSERT INTO scheduler_fts (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS scheduler_fts_delete AFTER DELETE ON scheduler BEGIN
+		INSERT INTO scheduler_fts (scheduler_fts, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS scheduler_fts_update AFTER UPDATE OF title, comment ON scheduler BEGIN
+		INSERT INTO scheduler_fts (scheduler_fts, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+		INSERT INTO scheduler_fts (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+	END;
+
+	INSERT INTO scheduler_fts (scheduler_fts) VALUES ('rebuild');
+	`,
+	// soft deletion, trashed tasks keep the unix time they were deleted at
+	`
+	ALTER TABLE scheduler ADD COLUMN deleted_at INTEGER;
+
+	CREATE INDEX IF NOT EXISTS idx_deleted_at ON scheduler (deleted_at);
+	`,
+	// archive of completed one-off tasks
+	`
+	ALTER TABLE scheduler ADD COLUMN completed_at INTEGER;
+
+	CREATE INDEX IF NOT EXISTS idx_completed_at ON scheduler (completed_at);
+	`,
+	// completion history, one row per occurrence marked as done
+	`
+	CREATE TABLE IF NOT EXISTS completions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id INTEGER NOT NULL REFERENCES scheduler (id) ON DELETE CASCADE,
+		scheduled_date TEXT NOT NULL,
+		completed_at INTEGER NOT NULL,
+		note TEXT NOT NULL DEFAULT ''
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_completions_task ON completions (task_id, completed_at);
+	`,
+	// append-only field-level audit trail, kept when tasks are purged
+	`
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		field TEXT NOT NULL,
+		old_value TEXT NOT NULL,
+		new_value TEXT NOT NULL,
+		actor TEXT NOT NULL,
+		changed_at INTEGER NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_audit_log_task ON audit_log (task_id, changed_at);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_changed_at ON audit_log (changed_at);
+
+	CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log BEGIN
+		SELECT RAISE(ABORT, 'audit log is append-only');
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log BEGIN
+		SELECT RAISE(ABORT, 'audit log is append-only');
+	END;
+	`,
+	// tags attached to tasks
+	`
+	CREATE TABLE IF NOT EXISTS tags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE
+	);
+
+	CREATE TABLE IF NOT EXISTS task_tags (
+		task_id INTEGER NOT NULL REFERENCES scheduler (id) ON DELETE CASCADE,
+		tag_id INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+		PRIMARY KEY (task_id, tag_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_task_tags_tag ON task_tags (tag_id);
+	`,
+	// projects grouping tasks, existing tasks go to the Inbox
+	`
+	CREATE TABLE IF NOT EXISTS projects (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL CHECK(LENGTH(name) <= 128),
+		inbox INTEGER NOT NULL DEFAULT 0,
+		created_at INTEGER NOT NULL
+	);
+
+	INSERT INTO projects (name, inbox, created_at) VALUES ('Inbox', 1, strftime('%s', 'now'));
+
+	ALTER TABLE scheduler ADD COLUMN project_id INTEGER REFERENCES projects (id);
+
+	UPDATE scheduler SET project_id = (SELECT id FROM projects WHERE inbox = 1);
+
+	CREATE INDEX IF NOT EXISTS idx_project ON scheduler (project_id, date);
+	`,
+	// task priority, an index into Priorities
+	`
+	ALTER TABLE scheduler ADD COLUMN priority INTEGER NOT NULL DEFAULT 0 CHECK(priority BETWEEN 0 AND 4);
+	`,
+	// subtasks form a checklist under a top-level parent task
+	`
+	ALTER TABLE scheduler ADD COLUMN parent_id INTEGER REFERENCES scheduler (id) ON DELETE CASCADE;
+	ALTER TABLE scheduler ADD COLUMN auto_complete INTEGER NOT NULL DEFAULT 0;
+
+	CREATE INDEX IF NOT EXISTS idx_parent ON scheduler (parent_id);
+	`,
+	// "blocked by" relation between tasks
+	`
+	CREATE TABLE IF NOT EXISTS task_dependencies (
+		task_id INTEGER NOT NULL REFERENCES scheduler (id) ON DELETE CASCADE,
+		blocker_id INTEGER NOT NULL REFERENCES scheduler (id) ON DELETE CASCADE,
+		PRIMARY KEY (task_id, blocker_id),
+		CHECK(task_id <> blocker_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_task_dependencies_blocker ON task_dependencies (blocker_id);
+	`,
+	// metadata of files attached to tasks, the files live on disk
+	`
+	CREATE TABLE IF NOT EXISTS attachments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id INTEGER NOT NULL REFERENCES scheduler (id) ON DELETE CASCADE,
+		name TEXT NOT NULL CHECK(LENGTH(name) <= 255),
+		size INTEGER NOT NULL,
+		mime_type TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		created_at INTEGER NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_attachments_task ON attachments (task_id);
+	`,
+	// notes thread of a task, next to the comment used as description
+	`
+	CREATE TABLE IF NOT EXISTS task_notes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id INTEGER NOT NULL REFERENCES scheduler (id) ON DELETE CASCADE,
+		author TEXT NOT NULL,
+		text TEXT NOT NULL CHECK(LENGTH(text) <= 4096),
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_task_notes_task ON task_notes (task_id, created_at);
+	`,
+	// user-defined typed fields, values keep the SQLite type of their field
+	`
+	CREATE TABLE IF NOT EXISTS field_definitions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE CHECK(LENGTH(name) <= 64),
+		type TEXT NOT NULL CHECK(type IN ('string', 'number', 'date', 'bool')),
+		created_at INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS task_fields (
+		task_id INTEGER NOT NULL REFERENCES scheduler (id) ON DELETE CASCADE,
+		field_id INTEGER NOT NULL REFERENCES field_definitions (id) ON DELETE CASCADE,
+		value NOT NULL,
+		PRIMARY KEY (task_id, field_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_task_fields_field ON task_fields (field_id, value);
+	`,
+	// optional due date next to the planned date
+	`
+	ALTER TABLE scheduler ADD COLUMN deadline INTEGER;
+
+	CREATE INDEX IF NOT EXISTS idx_deadline ON scheduler (deadline);
+	`,
+	// tasks hidden from listings until a day
+	`
+	ALTER TABLE scheduler ADD COLUMN defer_until INTEGER;
+	`,
+	// user accounts, existing tasks and projects go to the built-in admin
+	// who signs in with TODO_PASSWORD
+	`
+	CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE COLLATE NOCASE CHECK(LENGTH(name) <= 64),
+		password_hash TEXT NOT NULL,
+		admin INTEGER NOT NULL DEFAULT 0,
+		created_at INTEGER NOT NULL
+	);
+
+	INSERT INTO users (id, name, password_hash, admin, created_at) VALUES (1, 'admin', '', 1, strftime('%s', 'now'));
+
+	ALTER TABLE scheduler ADD COLUMN user_id INTEGER REFERENCES users (id) ON DELETE CASCADE;
+	ALTER TABLE projects ADD COLUMN user_id INTEGER REFERENCES users (id) ON DELETE CASCADE;
+
+	UPDATE scheduler SET user_id = 1;
+	UPDATE projects SET user_id = 1;
+
+	ALTER TABLE audit_log ADD COLUMN user_id INTEGER NOT NULL DEFAULT 1;
+
+	CREATE INDEX IF NOT EXISTS idx_user ON scheduler (user_id, date);
+	CREATE INDEX IF NOT EXISTS idx_projects_user ON projects (user_id);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_user ON audit_log (user_id, changed_at);
+	`,
+	// projects shared with other users, the owner is projects.user_id
+	`
+	CREATE TABLE IF NOT EXISTS project_members (
+		project_id INTEGER NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+		user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		role TEXT NOT NULL CHECK(role IN ('editor', 'viewer')),
+		created_at INTEGER NOT NULL,
+		PRIMARY KEY (project_id, user_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_project_members_user ON project_members (user_id);
+	`,
+	// user a task is assigned to and who assigned it
+	`
+	ALTER TABLE scheduler ADD COLUMN assignee_id INTEGER REFERENCES users (id) ON DELETE SET NULL;
+	ALTER TABLE scheduler ADD COLUMN assigned_by INTEGER REFERENCES users (id) ON DELETE SET NULL;
+
+	CREATE INDEX IF NOT EXISTS idx_assignee ON scheduler (assignee_id);
+	`,
+	// task version for optimistic concurrency, bumped on every change
+	`
+	ALTER TABLE scheduler ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+	`,
+	// note authors by id, the name of a deleted user can be taken again;
+	// anonymous notes were written by the built-in admin
+	`
+	ALTER TABLE task_notes ADD COLUMN author_id INTEGER REFERENCES users (id) ON DELETE SET NULL;
+
+	UPDATE task_notes SET author_id = IIF(author = 'anonymous', 1, (SELECT id FROM users WHERE name = task_notes.author));
+	`,
+}
+
+func migrate(ctx context.Context, db *sql.DB) error {
+	var version int
+
+	if err := db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d failed: %w", i+1, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
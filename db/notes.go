@@ -0,0 +1,165 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxNoteLength = 4096
+
+// Note is one entry of a task's notes thread. The task comment stays the
+// description of the task, notes are added next to it.
+type Note struct {
+	ID        string `json:"id"`
+	TaskID    string `json:"task_id"`
+	Author    string `json:"author"`
+	Text      string `json:"text"`
+	CreatedAt string `json:"created_at"`
+	// UpdatedAt is set once the note has been edited.
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+type NotesResponse struct {
+	Notes []Note `json:"notes"`
+}
+
+func NewNote(id, taskID, text string) (Note, error) {
+	text = strings.TrimSpace(text)
+
+	if text == "" {
+		return Note{}, fmt.Errorf("note should not be empty")
+	}
+
+	if len([]rune(text)) > maxNoteLength {
+		return Note{}, fmt.Errorf("note is too long")
+	}
+
+	return Note{ID: id, TaskID: taskID, Text: text}, nil
+}
+
+// CreateNote adds a note to a task that is not in the trash. The author
+// is the identity carried by the context, see WithUser; the name is kept
+// for display and the user id decides who may change the note.
+func (s *SqliteStorage) CreateNote(ctx context.Context, n Note) (string, error) {
+	query := `INSERT INTO task_notes (task_id, author, author_id, text, created_at)
+		SELECT id, $1, $2, $3, $4 FROM scheduler WHERE id=$5 AND deleted_at IS NULL AND project_id IN ` + visibleProjects("$2")
+
+	res, err := s.db.ExecContext(ctx, query, actorFrom(ctx), userID(ctx), n.Text, time.Now().Unix(), n.TaskID)
+	if err != nil {
+		return "", fmt.Errorf("failed to create note")
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+
+	if rows == 0 {
+		return "", fmt.Errorf("task not found id: %s", n.TaskID)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.Itoa(int(id)), nil
+}
+
+// GetNotes returns the notes thread of a task, the oldest first.
+func (s *SqliteStorage) GetNotes(ctx context.Context, taskID string) ([]Note, error) {
+	query := `SELECT id, task_id, author, text, created_at, updated_at FROM task_notes
+		WHERE task_id=$1 AND ` + userTask + ` ORDER BY created_at, id`
+
+	rows, err := s.db.QueryContext(ctx, query, taskID, userID(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	notes := []Note{}
+
+	for rows.Next() {
+		var (
+			n                    Note
+			createdAt, updatedAt sql.NullInt64
+		)
+
+		if err := rows.Scan(&n.ID, &n.TaskID, &n.Author, &n.Text, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+
+		n.CreatedAt = formatUnix(createdAt)
+		n.UpdatedAt = formatUnix(updatedAt)
+		notes = append(notes, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notes, nil
+}
+
+// GetNote returns a note of a task the user can see.
+func (s *SqliteStorage) GetNote(ctx context.Context, id string) (Note, error) {
+	var (
+		n                    Note
+		createdAt, updatedAt sql.NullInt64
+	)
+
+	query := `SELECT id, task_id, author, text, created_at, updated_at FROM task_notes WHERE id=$1 AND ` + userTask
+
+	err := s.db.QueryRowContext(ctx, query, id, userID(ctx)).Scan(&n.ID, &n.TaskID, &n.Author, &n.Text, &createdAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Note{}, fmt.Errorf("note not found id: %s", id)
+	} else if err != nil {
+		return Note{}, err
+	}
+
+	n.CreatedAt = formatUnix(createdAt)
+	n.UpdatedAt = formatUnix(updatedAt)
+
+	return n, nil
+}
+
+// UpdateNote changes the text of a note, only its author can edit it.
+func (s *SqliteStorage) UpdateNote(ctx context.Context, n Note) error {
+	query := `UPDATE task_notes SET text=$1, updated_at=$2 WHERE id=$3 AND author_id=$4`
+
+	res, err := s.db.ExecContext(ctx, query, n.Text, time.Now().Unix(), n.ID, userID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to update note")
+	}
+
+	return noteAffected(res, n.ID)
+}
+
+// DeleteNote removes a note, only its author can delete it.
+func (s *SqliteStorage) DeleteNote(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM task_notes WHERE id=$1 AND author_id=$2`, id, userID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete note")
+	}
+
+	return noteAffected(res, id)
+}
+
+func noteAffected(res sql.Result, id string) error {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("note not found id: %s", id)
+	}
+
+	return nil
+}
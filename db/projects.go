@@ -0,0 +1,310 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const maxProjectName = 128
+
+type ProjectDeleteMode string
+
+const (
+	// ProjectReassign moves the tasks of a deleted project to another one.
+	ProjectReassign ProjectDeleteMode = "reassign"
+	// ProjectCascade moves the tasks of a deleted project to the trash.
+	ProjectCascade ProjectDeleteMode = "cascade"
+)
+
+type Project struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Inbox bool   `json:"inbox"`
+	// Count is the number of active tasks in the project.
+	Count int `json:"count"`
+	// Role is the permission of the user on the project, see ShareProject.
+	Role Role `json:"role,omitempty"`
+}
+
+type ProjectsResponse struct {
+	Projects []Project `json:"projects"`
+}
+
+func NewProject(id, name string) (Project, error) {
+	name = strings.TrimSpace(name)
+
+	if name == "" {
+		return Project{}, fmt.Errorf("project name should not be empty")
+	}
+
+	if len([]rune(name)) > maxProjectName {
+		return Project{}, fmt.Errorf("project name is too long")
+	}
+
+	return Project{ID: id, Name: name}, nil
+}
+
+// GetProjects lists the projects of the user followed by the ones shared
+// with it.
+func (s *SqliteStorage) GetProjects(ctx context.Context) ([]Project, error) {
+	query := `SELECT p.id, p.name, p.inbox, COUNT(s.id), IIF(p.user_id=$1, 'owner', m.role) AS role FROM projects p
+		LEFT JOIN (SELECT id, project_id FROM scheduler WHERE ` + activeTask + `) s ON s.project_id = p.id
+		LEFT JOIN project_members m ON m.project_id = p.id AND m.user_id=$1
+		WHERE p.id IN ` + visibleProjects("$1") + `
+		GROUP BY p.id ORDER BY p.inbox DESC, role <> 'owner', p.name`
+
+	rows, err := s.db.QueryContext(ctx, query, userID(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	projects := []Project{}
+
+	for rows.Next() {
+		var p Project
+		if err := rows.Scan(&p.ID, &p.Name, &p.Inbox, &p.Count, &p.Role); err != nil {
+			return nil, err
+		}
+		projects = append(projects, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+func (s *SqliteStorage) CreateProject(ctx context.Context, p Project) (string, error) {
+	query := `INSERT INTO projects (name, created_at, user_id) VALUES ($1, $2, $3)`
+
+	res, err := s.db.ExecContext(ctx, query, p.Name, time.Now().Unix(), userID(ctx))
+	if err != nil {
+		return "", fmt.Errorf("failed to create project")
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprint(id), nil
+}
+
+func (s *SqliteStorage) UpdateProject(ctx context.Context, p Project) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE projects SET name=$1 WHERE id=$2 AND user_id=$3`, p.Name, p.ID, userID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to update project")
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("project not found id: %s", p.ID)
+	}
+
+	return nil
+}
+
+// DeleteProject removes a project. Its tasks are moved to the target
+// project, or to the Inbox when target is empty. With ProjectCascade the
+// tasks are also moved to the trash, so restoring one puts it in the Inbox.
+// The Inbox itself can't be deleted.
+func (s *SqliteStorage) DeleteProject(ctx context.Context, id string, mode ProjectDeleteMode, target string) error {
+	if mode != ProjectReassign && mode != ProjectCascade {
+		return fmt.Errorf("unknown delete mode %s", mode)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var inbox bool
+
+	err = tx.QueryRowContext(ctx, `SELECT inbox FROM projects WHERE id=$1 AND user_id=$2`, id, userID(ctx)).Scan(&inbox)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("project not found id: %s", id)
+	} else if err != nil {
+		return err
+	}
+
+	if inbox {
+		return fmt.Errorf("inbox can't be deleted")
+	}
+
+	if mode == ProjectCascade {
+		target = ""
+	}
+
+	target, err = taskProject(ctx, tx, target)
+	if err != nil {
+		return err
+	}
+
+	if target == id {
+		return fmt.Errorf("can't move tasks to the deleted project")
+	}
+
+	ids, err := projectTasks(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+
+	for _, taskID := range ids {
+		action := auditMove
+		if mode == ProjectCascade {
+			action = auditDelete
+		}
+
+		_, err := auditTx(ctx, tx, action, taskID, func(tx *sql.Tx) (string, error) {
+			query := `UPDATE scheduler SET project_id=$1, user_id=` + projectOwner + ` WHERE id=$2`
+			args := []any{target, taskID}
+
+			if mode == ProjectCascade {
+				query = `UPDATE scheduler SET project_id=$1, user_id=` + projectOwner + `, deleted_at=IFNULL(deleted_at, $2) WHERE id=$3`
+				args = []any{target, now, taskID}
+			}
+
+			if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+				return "", fmt.Errorf("failed to move task id: %s", taskID)
+			}
+
+			return taskID, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM projects WHERE id=$1`, id); err != nil {
+		return fmt.Errorf("failed to delete project")
+	}
+
+	return tx.Commit()
+}
+
+// projectOwner selects the owner of the project bound to $1, tasks
+// belong to the owner of their project.
+const projectOwner = `(SELECT user_id FROM projects WHERE id=$1)`
+
+// moveTask moves a top-level task together with its subtasks to a project
+// the user can see, an empty project id stands for the Inbox.
+func moveTask(ctx context.Context, tx *sql.Tx, id, projectID string, version int) error {
+	target, err := taskProject(ctx, tx, projectID)
+	if err != nil {
+		return err
+	}
+
+	var parentID sql.NullString
+
+	query := `SELECT parent_id FROM scheduler WHERE id=$1 AND project_id IN ` + visibleProjects("$2") + ` AND ` + activeTask
+
+	err = tx.QueryRowContext(ctx, query, id, userID(ctx)).Scan(&parentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("task not found id: %s", id)
+	} else if err != nil {
+		return err
+	}
+
+	if parentID.Valid {
+		return fmt.Errorf("subtasks move with their parent")
+	}
+
+	if err := checkVersion(ctx, tx, id, version); err != nil {
+		return err
+	}
+
+	subtasks, err := childTasks(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	return moveTasks(ctx, tx, append([]string{id}, subtasks...), target)
+}
+
+// moveTasks puts the tasks into the project, each move is audited.
+func moveTasks(ctx context.Context, tx *sql.Tx, ids []string, projectID string) error {
+	for _, taskID := range ids {
+		_, err := auditTx(ctx, tx, auditMove, taskID, func(tx *sql.Tx) (string, error) {
+			query := `UPDATE scheduler SET project_id=$1, user_id=` + projectOwner + ` WHERE id=$2`
+
+			if _, err := tx.ExecContext(ctx, query, projectID, taskID); err != nil {
+				return "", fmt.Errorf("failed to move task id: %s", taskID)
+			}
+
+			return taskID, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// taskProject checks that a project the user can see exists and returns
+// its id, an empty id stands for the user's Inbox.
+func taskProject(ctx context.Context, tx *sql.Tx, id string) (string, error) {
+	query := `SELECT id FROM projects WHERE id=$1 AND id IN ` + visibleProjects("$2")
+	args := []any{id, userID(ctx)}
+
+	if id == "" {
+		query = `SELECT id FROM projects WHERE inbox=1 AND user_id=$1`
+		args = []any{userID(ctx)}
+	}
+
+	var projectID string
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("project not found id: %s", id)
+	} else if err != nil {
+		return "", err
+	}
+
+	return projectID, nil
+}
+
+func projectTasks(ctx context.Context, tx *sql.Tx, projectID string) ([]string, error) {
+	return taskIDs(ctx, tx, `SELECT id FROM scheduler WHERE project_id=$1`, projectID)
+}
+
+// childTasks lists the subtasks of a task, trashed and completed ones too.
+func childTasks(ctx context.Context, tx *sql.Tx, parentID string) ([]string, error) {
+	return taskIDs(ctx, tx, `SELECT id FROM scheduler WHERE parent_id=$1`, parentID)
+}
+
+func taskIDs(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids []string
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
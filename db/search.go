@@ -0,0 +1,81 @@
+package db
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// TextTerm is a single full-text condition of a TaskFilter.
+type TextTerm struct {
+	// Field limits the term to "title" or "comment", empty means both.
+	Field string
+	Value string
+	// Phrase terms match the exact sequence of words, other terms match
+	// every word as a prefix so that partially typed words still find tasks.
+	Phrase bool
+	Negate bool
+}
+
+var textFields = map[string]bool{"": true, "title": true, "comment": true}
+
+// snippet() wraps matches into control characters instead of tags, so
+// the text around them can be escaped before they turn into <mark> tags.
+var marks = strings.NewReplacer("\x02", "<mark>", "\x03", "</mark>")
+
+// highlight turns a snippet of user text into safe HTML.
+func highlight(snippet string) string {
+	return marks.Replace(html.EscapeString(snippet))
+}
+
+// ftsMatch joins terms into one FTS5 MATCH expression where every term
+// has to match. Terms without letters or digits are skipped because the
+// tokenizer would drop them anyway.
+func ftsMatch(terms []TextTerm) string {
+	var parts []string
+
+	for _, term := range terms {
+		if !hasWordChars(term.Value) {
+			continue
+		}
+
+		part := quoteFTS(term.Value)
+		if !term.Phrase {
+			part = ftsPrefix(term.Value)
+		}
+
+		if term.Field != "" {
+			part = term.Field + " : " + part
+		}
+
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, " AND ")
+}
+
+// ftsPrefix matches every word of value as a prefix.
+func ftsPrefix(value string) string {
+	words := strings.Fields(strings.TrimRight(value, "*"))
+	for i, w := range words {
+		words[i] = quoteFTS(w) + "*"
+	}
+
+	if len(words) == 1 {
+		return words[0]
+	}
+
+	return "(" + strings.Join(words, " AND ") + ")"
+}
+
+// quoteFTS wraps s into an FTS5 string so that operators and
+// punctuation typed by the user are never interpreted by the parser.
+func quoteFTS(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func hasWordChars(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}) >= 0
+}
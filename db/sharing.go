@@ -0,0 +1,208 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Role is the permission of a user on a project and its tasks.
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// Allows reports whether the role grants at least the permissions of need.
+func (r Role) Allows(need Role) bool {
+	return roleRanks[r] >= roleRanks[need]
+}
+
+// ParseMemberRole checks a role that can be given to a member, the owner
+// role can't.
+func ParseMemberRole(s string) (Role, error) {
+	switch r := Role(strings.ToLower(s)); r {
+	case RoleEditor, RoleViewer:
+		return r, nil
+	}
+
+	return "", fmt.Errorf("unknown role %s", s)
+}
+
+type Member struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	Role   Role   `json:"role"`
+}
+
+type MembersResponse struct {
+	Members []Member `json:"members"`
+}
+
+// visibleProjects selects the projects a user owns or is a member of,
+// param is the placeholder the user id is bound to.
+func visibleProjects(param string) string {
+	return `(SELECT id FROM projects WHERE user_id=` + param +
+		` UNION SELECT project_id FROM project_members WHERE user_id=` + param + `)`
+}
+
+// editableProjects selects the projects a user owns or edits, param is
+// the placeholder the user id is bound to.
+func editableProjects(param string) string {
+	return `(SELECT id FROM projects WHERE user_id=` + param +
+		` UNION SELECT project_id FROM project_members WHERE user_id=` + param + ` AND role='editor')`
+}
+
+// ProjectRole returns the role of the user on a project it can see.
+func (s *SqliteStorage) ProjectRole(ctx context.Context, projectID string) (Role, error) {
+	query := `SELECT IIF(p.user_id=$1, 'owner', m.role) FROM projects p
+		LEFT JOIN project_members m ON m.project_id = p.id AND m.user_id=$1
+		WHERE p.id=$2 AND p.id IN ` + visibleProjects("$1")
+
+	return s.queryRole(ctx, query, projectID, fmt.Errorf("project not found id: %s", projectID))
+}
+
+// TaskRole returns the role of the user on the project of a task, trashed
+// and completed tasks included.
+func (s *SqliteStorage) TaskRole(ctx context.Context, taskID string) (Role, error) {
+	query := `SELECT IIF(p.user_id=$1, 'owner', m.role) FROM scheduler s
+		JOIN projects p ON p.id = s.project_id
+		LEFT JOIN project_members m ON m.project_id = p.id AND m.user_id=$1
+		WHERE s.id=$2 AND p.id IN ` + visibleProjects("$1")
+
+	return s.queryRole(ctx, query, taskID, fmt.Errorf("task not found id: %s", taskID))
+}
+
+func (s *SqliteStorage) queryRole(ctx context.Context, query, id string, notFound error) (Role, error) {
+	var role Role
+
+	err := s.db.QueryRowContext(ctx, query, userID(ctx), id).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", notFound
+	} else if err != nil {
+		return "", err
+	}
+
+	return role, nil
+}
+
+// GetMembers lists the users a project is shared with, the owner first.
+func (s *SqliteStorage) GetMembers(ctx context.Context, projectID string) ([]Member, error) {
+	query := `SELECT u.id, u.name, 'owner', 0 FROM projects p JOIN users u ON u.id = p.user_id
+			WHERE p.id=$1 AND p.id IN ` + visibleProjects("$2") + `
+		UNION ALL
+		SELECT u.id, u.name, m.role, 1 FROM project_members m JOIN users u ON u.id = m.user_id
+			WHERE m.project_id=$1 AND m.project_id IN ` + visibleProjects("$2") + `
+		ORDER BY 4, 2`
+
+	rows, err := s.db.QueryContext(ctx, query, projectID, userID(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	members := []Member{}
+
+	for rows.Next() {
+		var (
+			m     Member
+			order int
+		)
+
+		if err := rows.Scan(&m.UserID, &m.Name, &m.Role, &order); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(members) == 0 {
+		return nil, fmt.Errorf("project not found id: %s", projectID)
+	}
+
+	return members, nil
+}
+
+// ShareProject invites a user by name to a project of the owner. The
+// Inbox is personal and can't be shared.
+func (s *SqliteStorage) ShareProject(ctx context.Context, projectID, name string, role Role) (Member, error) {
+	user, err := s.GetUserByName(ctx, name)
+	if err != nil {
+		return Member{}, err
+	}
+
+	query := `INSERT INTO project_members (project_id, user_id, role, created_at)
+		SELECT id, $1, $2, $3 FROM projects WHERE id=$4 AND user_id=$5 AND user_id<>$1 AND inbox=0
+		ON CONFLICT (project_id, user_id) DO NOTHING`
+
+	res, err := s.db.ExecContext(ctx, query, user.ID, role, time.Now().Unix(), projectID, userID(ctx))
+	if err != nil {
+		return Member{}, fmt.Errorf("failed to share project")
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return Member{}, err
+	}
+
+	if rows == 0 {
+		return Member{}, fmt.Errorf("can't share project id: %s with %s", projectID, user.Name)
+	}
+
+	return Member{UserID: user.ID, Name: user.Name, Role: role}, nil
+}
+
+// SetMemberRole changes the role of a member of a project of the owner.
+func (s *SqliteStorage) SetMemberRole(ctx context.Context, projectID, memberID string, role Role) error {
+	query := `UPDATE project_members SET role=$1
+		WHERE project_id=$2 AND user_id=$3 AND project_id IN (SELECT id FROM projects WHERE user_id=$4)`
+
+	res, err := s.db.ExecContext(ctx, query, role, projectID, memberID, userID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to change role")
+	}
+
+	return memberAffected(res, memberID)
+}
+
+// RevokeMember removes a member from a project. The owner can revoke
+// anyone, members can leave on their own.
+func (s *SqliteStorage) RevokeMember(ctx context.Context, projectID, memberID string) error {
+	query := `DELETE FROM project_members WHERE project_id=$1 AND user_id=$2
+		AND (user_id=$3 OR project_id IN (SELECT id FROM projects WHERE user_id=$3))`
+
+	res, err := s.db.ExecContext(ctx, query, projectID, memberID, userID(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to revoke member")
+	}
+
+	return memberAffected(res, memberID)
+}
+
+func memberAffected(res sql.Result, id string) error {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("member not found id: %s", id)
+	}
+
+	return nil
+}
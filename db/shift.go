@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zeze322/todo/lib"
+	"github.com/zeze322/todo/repeattask"
+)
+
+// TaskShift reschedules the active tasks dated between From and To, both
+// inclusive in lib.Layout, an empty From has no lower bound. Tasks move
+// Days later or, with Today set, to today. Repeating tasks aren't shifted
+// by the offset, they move to the first date their rule gives from the
+// target day on.
+type TaskShift struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Days  int    `json:"days"`
+	Today bool   `json:"today"`
+	// DryRun only lists the tasks that would move.
+	DryRun bool `json:"dry_run"`
+}
+
+// ShiftedTask is a task moved by ShiftTasks from Date to NewDate.
+type ShiftedTask struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Repeat  string `json:"repeat,omitempty"`
+	Date    string `json:"date"`
+	NewDate string `json:"new_date"`
+}
+
+type ShiftResponse struct {
+	Tasks  []ShiftedTask `json:"tasks"`
+	DryRun bool          `json:"dry_run"`
+}
+
+func (sh TaskShift) Validate() error {
+	for _, date := range []string{sh.From, sh.To} {
+		if date == "" {
+			continue
+		}
+
+		if _, err := time.Parse(lib.Layout, date); err != nil {
+			return fmt.Errorf("invalid date %s", date)
+		}
+	}
+
+	if sh.To == "" {
+		return fmt.Errorf("to not specified")
+	}
+
+	if sh.From > sh.To {
+		return fmt.Errorf("from is after to")
+	}
+
+	if sh.Today {
+		if sh.Days != 0 {
+			return fmt.Errorf("days can't be set together with today")
+		}
+
+		if sh.To >= today() {
+			return fmt.Errorf("only past tasks can be moved to today")
+		}
+
+		return nil
+	}
+
+	if sh.Days < 1 {
+		return fmt.Errorf("days should be positive")
+	}
+
+	return nil
+}
+
+// target returns the day a task on date moves to.
+func (sh TaskShift) target(date string) string {
+	if sh.Today {
+		return today()
+	}
+
+	t, _ := time.Parse(lib.Layout, date)
+	return t.AddDate(0, 0, sh.Days).Format(lib.Layout)
+}
+
+func today() string {
+	return time.Now().Format(lib.Layout)
+}
+
+// ShiftTasks reschedules the tasks matched by shift in one transaction
+// and returns them with their new dates, oldest first. Only tasks of the
+// projects the user owns or edits are moved, this scope is the permission
+// check of the method, see api guardedStorage. A dry run changes nothing.
+func (s *SqliteStorage) ShiftTasks(ctx context.Context, shift TaskShift) ([]ShiftedTask, error) {
+	if err := shift.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	query := `SELECT ` + taskColumns + `, '' FROM scheduler s
+		WHERE s.project_id IN ` + editableProjects("$1") + ` AND ($2 = '' OR s.date >= $2) AND s.date <= $3 AND ` + activeTask + `
+		ORDER BY s.date, s.id`
+
+	rows, err := tx.QueryContext(ctx, query, userID(ctx), shift.From, shift.To)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	shifted := make([]ShiftedTask, 0, len(tasks))
+
+	for _, task := range tasks {
+		date := shift.target(task.Date)
+
+		if task.Repeat != "" {
+			now, _ := time.Parse(lib.Layout, date)
+
+			if date, err = repeattask.NextDate(now, task.Date, task.Repeat); err != nil {
+				return nil, fmt.Errorf("task id: %s: %w", task.ID, err)
+			}
+		}
+
+		if date == task.Date {
+			continue
+		}
+
+		shifted = append(shifted, ShiftedTask{ID: task.ID, Title: task.Title, Repeat: task.Repeat, Date: task.Date, NewDate: date})
+
+		if shift.DryRun {
+			continue
+		}
+
+		if err := rescheduleTask(ctx, tx, task, date); err != nil {
+			return nil, err
+		}
+	}
+
+	if shift.DryRun {
+		return shifted, nil
+	}
+
+	return shifted, tx.Commit()
+}
@@ -5,23 +5,74 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
-	"github.com/zeze322/todo/lib"
 )
 
 const limit = 25
 
+// maxLimit caps the number of tasks or audit entries one request can ask
+// for.
+const maxLimit = 500
+
 type Storage interface {
 	CreateTask(context.Context, Task) (string, error)
-	GetTasks(context.Context, string) ([]Task, error)
+	GetTasks(context.Context, TaskFilter) ([]Task, error)
 	GetTask(context.Context, string) (Task, error)
 	UpdateTask(context.Context, string, Task) error
 	DeleteTask(context.Context, string) error
+	GetTrash(context.Context) ([]Task, error)
+	RestoreTask(context.Context, string) error
+	PurgeTrash(context.Context, time.Time) (int64, error)
+	GetCompletedTasks(context.Context, time.Time, time.Time) ([]Task, error)
+	UncompleteTask(context.Context, string) error
+	CompleteTask(context.Context, string, string, int) (Task, error)
+	Batch(context.Context, []BatchOp, BatchMode) ([]BatchResult, error)
+	ShiftTasks(context.Context, TaskShift) ([]ShiftedTask, error)
+	Backup(context.Context, io.Writer) error
+	Restore(context.Context, io.Reader) error
+	GetCompletions(context.Context, string) ([]Completion, error)
+	UndoCompletion(context.Context, string) (Task, error)
+	GetAudit(context.Context, AuditFilter) ([]AuditEntry, error)
+	GetTags(context.Context) ([]TagCount, error)
+	GetProjects(context.Context) ([]Project, error)
+	CreateProject(context.Context, Project) (string, error)
+	UpdateProject(context.Context, Project) error
+	DeleteProject(context.Context, string, ProjectDeleteMode, string) error
+	CreateAttachment(context.Context, Attachment) (string, error)
+	GetAttachments(context.Context, string) ([]Attachment, error)
+	GetAttachment(context.Context, string) (Attachment, error)
+	DeleteAttachment(context.Context, string) error
+	GetAttachmentIDs(context.Context) (map[string]bool, error)
+	CreateNote(context.Context, Note) (string, error)
+	GetNotes(context.Context, string) ([]Note, error)
+	GetNote(context.Context, string) (Note, error)
+	UpdateNote(context.Context, Note) error
+	DeleteNote(context.Context, string) error
+	GetFields(context.Context) ([]FieldDefinition, error)
+	CreateField(context.Context, FieldDefinition) (string, error)
+	UpdateField(context.Context, FieldDefinition) error
+	DeleteField(context.Context, string) error
+	GetUsers(context.Context) ([]User, error)
+	GetUser(context.Context, string) (User, error)
+	GetUserByName(context.Context, string) (User, error)
+	CreateUser(context.Context, User) (string, error)
+	UpdateUserPassword(context.Context, string, string) error
+	DeleteUser(context.Context, string) error
+	ProjectRole(context.Context, string) (Role, error)
+	TaskRole(context.Context, string) (Role, error)
+	GetMembers(context.Context, string) ([]Member, error)
+	ShareProject(context.Context, string, string, Role) (Member, error)
+	SetMemberRole(context.Context, string, string, Role) error
+	RevokeMember(context.Context, string, string) error
 }
 
 type SqliteStorage struct {
@@ -29,7 +80,10 @@ type SqliteStorage struct {
 }
 
 func NewStorage(storagePath string) (*SqliteStorage, error) {
-	db, err := sql.Open("sqlite3", storagePath)
+	// write transactions take the write lock with BEGIN IMMEDIATE, so a
+	// transaction that reads a task before changing it can't be overtaken
+	// by another writer in between
+	db, err := sql.Open("sqlite3", "file:"+storagePath+"?_foreign_keys=on&_txlock=immediate")
 	if err != nil {
 		return nil, err
 	}
@@ -85,6 +139,21 @@ func NewStorage(storagePath string) (*SqliteStorage, error) {
 
 	}
 
+	// the search index and its triggers on scheduler need FTS5, which
+	// go-sqlite3 only compiles in with the sqlite_fts5 build tag
+	var fts5 bool
+	if err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5); err != nil {
+		return nil, err
+	}
+
+	if !fts5 {
+		return nil, fmt.Errorf("SQLite is built without FTS5, build with -tags sqlite_fts5")
+	}
+
+	if err := migrate(context.Background(), db); err != nil {
+		return nil, err
+	}
+
 	return &SqliteStorage{
 		db: db,
 	}, nil
@@ -95,131 +164,203 @@ func (s *SqliteStorage) Close() error {
 }
 
 func (s *SqliteStorage) CreateTask(ctx context.Context, task Task) (string, error) {
-	query := `INSERT INTO scheduler (date, title, comment, repeat) VALUES ($1, $2, $3, $4)`
-
-	res, err := s.db.ExecContext(ctx, query, task.Date, task.Title, task.Comment, task.Repeat)
+	created, err := s.withAudit(ctx, auditCreate, "", func(tx *sql.Tx) (string, error) {
+		return insertTask(ctx, tx, task)
+	})
 	if err != nil {
 		return "", err
 	}
 
-	id, err := res.LastInsertId()
+	return created.ID, nil
+}
+
+// insertTask adds a task, it runs inside auditTx.
+func insertTask(ctx context.Context, tx *sql.Tx, task Task) (string, error) {
+	projectID, err := taskProject(ctx, tx, task.ProjectID)
 	if err != nil {
 		return "", err
 	}
 
-	return strconv.Itoa(int(id)), nil
-}
+	priority, err := ParsePriority(task.Priority)
+	if err != nil {
+		return "", err
+	}
 
-func (s *SqliteStorage) GetTasks(ctx context.Context, keyWord string) ([]Task, error) {
-	if keyWord == "" {
-		query := `SELECT id, date, title, comment, repeat FROM scheduler ORDER BY date ASC LIMIT $1`
+	var parentID sql.NullString
 
-		rows, err := s.db.Query(query, limit)
-		if err != nil {
-			return nil, err
+	if task.ParentID != "" {
+		if projectID, err = subtaskParent(ctx, tx, task); err != nil {
+			return "", err
 		}
+		parentID = sql.NullString{String: task.ParentID, Valid: true}
+	}
 
-		defer rows.Close()
+	query := `INSERT INTO scheduler (date, title, comment, repeat, project_id, priority, parent_id, auto_complete, deadline, defer_until, user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), (SELECT user_id FROM projects WHERE id=$5))`
 
-		var tasks []Task
+	res, err := tx.ExecContext(ctx, query, task.Date, task.Title, task.Comment, task.Repeat, projectID, priority,
+		parentID, task.AutoComplete != nil && *task.AutoComplete, task.Deadline, task.DeferUntil)
+	if err != nil {
+		return "", err
+	}
 
-		for rows.Next() {
-			task := Task{}
-			if err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat); err != nil {
-				return nil, err
-			}
-			tasks = append(tasks, task)
-		}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return "", err
+	}
 
-		if err = rows.Err(); err != nil {
-			log.Println(err)
-		}
+	id := strconv.Itoa(int(lastID))
 
-		if len(tasks) == 0 {
-			return []Task{}, nil
-		}
+	if err := setTags(ctx, tx, id, task.Tags); err != nil {
+		return "", err
+	}
 
-		return tasks, nil
+	if err := setFields(ctx, tx, id, task.Fields); err != nil {
+		return "", err
 	}
 
-	if keyWord != "" && lib.IsDate(keyWord) {
-		date, err := lib.ParseTime(keyWord)
-		if err != nil {
-			return nil, err
+	if task.Assignee != nil {
+		if err := setAssignee(ctx, tx, id, *task.Assignee); err != nil {
+			return "", err
 		}
+	}
 
-		query := `SELECT id, date, title, comment, repeat FROM scheduler WHERE date=$1`
+	return id, setDependencies(ctx, tx, id, task.BlockedBy)
+}
 
-		rows, err := s.db.Query(query, date)
-		if err != nil {
-			return nil, err
-		}
+func (s *SqliteStorage) GetTasks(ctx context.Context, filter TaskFilter) ([]Task, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
 
-		defer rows.Close()
+	query, args := filter.query(userID(ctx))
 
-		var tasks []Task
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
 
-		for rows.Next() {
-			task := Task{}
-			if err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat); err != nil {
-				return nil, err
-			}
-			tasks = append(tasks, task)
-		}
+	return scanTasks(rows)
+}
 
-		if err = rows.Err(); err != nil {
-			log.Println(err)
-		}
+// taskColumns are the columns read by scanTask, the scheduler table
+// must be aliased as s. Queries add the search snippet as the last column.
+const taskColumns = `s.id, s.date, s.title, s.comment, s.repeat, s.deleted_at, s.completed_at, s.project_id, s.priority,
+	(SELECT group_concat(t.name) FROM task_tags tt JOIN tags t ON t.id = tt.tag_id WHERE tt.task_id = s.id),
+	s.parent_id, s.auto_complete,
+	(SELECT COUNT(*) FROM scheduler c WHERE c.parent_id = s.id AND c.deleted_at IS NULL),
+	(SELECT COUNT(*) FROM scheduler c WHERE c.parent_id = s.id AND c.deleted_at IS NULL AND c.completed_at IS NOT NULL),
+	(SELECT group_concat(d.blocker_id) FROM task_dependencies d WHERE d.task_id = s.id), ` + blockedTask + `,
+	(SELECT COUNT(*) FROM task_notes n WHERE n.task_id = s.id), s.deadline, s.defer_until, s.assignee_id, s.assigned_by, s.version,
+	(SELECT json_group_object(f.name, CASE f.type WHEN 'bool' THEN json(IIF(tf.value, 'true', 'false')) ELSE tf.value END)
+		FROM task_fields tf JOIN field_definitions f ON f.id = tf.field_id WHERE tf.task_id = s.id)`
+
+// activeTask matches tasks that are neither in the trash nor completed.
+const activeTask = `deleted_at IS NULL AND completed_at IS NULL`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
 
-		if len(tasks) == 0 {
-			return []Task{}, nil
-		}
+func scanTask(row scanner) (Task, error) {
+	var (
+		task                   Task
+		deletedAt, completedAt sql.NullInt64
+		projectID, tags        sql.NullString
+		parentID, blockedBy    sql.NullString
+		fields, deadline       sql.NullString
+		deferUntil             sql.NullString
+		assignee, assignedBy   sql.NullString
+		priority               int
+		autoComplete           bool
+		progress               Progress
+	)
+
+	if err := row.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat, &deletedAt, &completedAt, &projectID, &priority, &tags,
+		&parentID, &autoComplete, &progress.Total, &progress.Done, &blockedBy, &task.Blocked, &task.Notes, &deadline, &deferUntil, &assignee, &assignedBy, &task.Version, &fields, &task.Snippet); err != nil {
+		return Task{}, err
+	}
 
-		return tasks, nil
+	task.ParentID = parentID.String
+	task.Snippet = highlight(task.Snippet)
+
+	if progress.Total > 0 {
+		task.Progress = &progress
 	}
 
-	if keyWord != "" && !lib.IsDate(keyWord) {
-		query := `SELECT id, date, title, comment, repeat FROM scheduler WHERE LOWER(title) LIKE $1 OR LOWER(comment) LIKE $1`
-		keyWord = "%" + keyWord + "%"
+	if autoComplete {
+		task.AutoComplete = &autoComplete
+	}
 
-		rows, err := s.db.Query(query, keyWord)
-		if err != nil {
-			return nil, err
-		}
+	if priority >= 0 && priority < len(Priorities) {
+		task.Priority = Priorities[priority]
+	}
 
-		defer rows.Close()
+	task.ProjectID = projectID.String
+	task.DeletedAt = formatUnix(deletedAt)
+	task.CompletedAt = formatUnix(completedAt)
 
-		var tasks []Task
+	if tags.Valid {
+		task.Tags = strings.Split(tags.String, ",")
+		sort.Strings(task.Tags)
+	}
 
-		for rows.Next() {
-			task := Task{}
-			if err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat); err != nil {
-				return nil, err
-			}
-			tasks = append(tasks, task)
-		}
+	task.Fields = decodeFields(fields)
 
-		if err = rows.Err(); err != nil {
-			log.Println(err)
-		}
+	if deadline.Valid {
+		task.Deadline = &deadline.String
+	}
+
+	if deferUntil.Valid {
+		task.DeferUntil = &deferUntil.String
+	}
+
+	if assignee.Valid {
+		task.Assignee = &assignee.String
+	}
+
+	task.AssignedBy = assignedBy.String
+
+	if blockedBy.Valid {
+		task.BlockedBy, _ = NormalizeBlockers(strings.Split(blockedBy.String, ","))
+	}
+
+	return task, nil
+}
+
+func formatUnix(t sql.NullInt64) string {
+	if !t.Valid {
+		return ""
+	}
+
+	return time.Unix(t.Int64, 0).UTC().Format(time.RFC3339)
+}
+
+func scanTasks(rows *sql.Rows) ([]Task, error) {
+	defer rows.Close()
 
-		if len(tasks) == 0 {
-			return []Task{}, nil
+	tasks := []Task{}
+
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
 		}
+		tasks = append(tasks, task)
+	}
 
-		return tasks, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return nil, nil
+	return tasks, nil
 }
 
 func (s *SqliteStorage) GetTask(ctx context.Context, id string) (Task, error) {
-	query := `SELECT id, date, title, comment, repeat FROM scheduler WHERE id=$1`
-	row := s.db.QueryRowContext(ctx, query, id)
-
-	var task Task
+	query := `SELECT ` + taskColumns + `, '' FROM scheduler s WHERE s.id=$1 AND s.project_id IN ` + visibleProjects("$2") + ` AND ` + activeTask
+	row := s.db.QueryRowContext(ctx, query, id, userID(ctx))
 
-	err := row.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+	task, err := scanTask(row)
 	if errors.Is(err, sql.ErrNoRows) {
 		return Task{}, fmt.Errorf("task not found id: %s", id)
 	} else if err != nil {
@@ -230,55 +371,166 @@ func (s *SqliteStorage) GetTask(ctx context.Context, id string) (Task, error) {
 }
 
 func (s *SqliteStorage) UpdateTask(ctx context.Context, id string, task Task) error {
-	query := `UPDATE scheduler SET date=$1, title=$2, comment=$3, repeat=$4 WHERE id=$5`
+	_, err := s.withAudit(ctx, auditUpdate, id, func(tx *sql.Tx) (string, error) {
+		return updateTask(ctx, tx, id, task)
+	})
+
+	return err
+}
 
-	stmt, err := s.db.PrepareContext(ctx, query)
+// projectSubtasks returns the subtasks that follow the task into the project.
+// A subtask itself can't leave its parent's project.
+func projectSubtasks(ctx context.Context, tx *sql.Tx, id, projectID string) ([]string, error) {
+	var (
+		current  sql.NullString
+		parentID sql.NullString
+	)
+
+	query := `SELECT project_id, parent_id FROM scheduler WHERE id=$1`
+
+	err := tx.QueryRowContext(ctx, query, id).Scan(&current, &parentID)
+	if errors.Is(err, sql.ErrNoRows) || current.String == projectID {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if parentID.Valid {
+		return nil, fmt.Errorf("subtasks move with their parent")
+	}
+
+	return childTasks(ctx, tx, id)
+}
+
+// updateTask changes a task, it runs inside auditTx.
+func updateTask(ctx context.Context, tx *sql.Tx, id string, task Task) (string, error) {
+	var (
+		projectID sql.NullString
+		subtasks  []string
+	)
+
+	if task.ProjectID != "" {
+		target, err := taskProject(ctx, tx, task.ProjectID)
+		if err != nil {
+			return "", err
+		}
+		projectID = sql.NullString{String: target, Valid: true}
+
+		if subtasks, err = projectSubtasks(ctx, tx, id, target); err != nil {
+			return "", err
+		}
+	}
+
+	var priority sql.NullInt64
+
+	if task.Priority != "" {
+		p, err := ParsePriority(task.Priority)
+		if err != nil {
+			return "", err
+		}
+		priority = sql.NullInt64{Int64: int64(p), Valid: true}
+	}
+
+	if task.Repeat != "" {
+		if err := checkRepeatAllowed(ctx, tx, id); err != nil {
+			return "", err
+		}
+	}
+
+	if task.Deadline == nil {
+		if err := checkDeadline(ctx, tx, id, task.Date); err != nil {
+			return "", err
+		}
+	}
+
+	query := `UPDATE scheduler SET date=$1, title=$2, comment=$3, repeat=$4,
+		project_id=IFNULL($5, project_id), user_id=IFNULL((SELECT user_id FROM projects WHERE id=$5), user_id), priority=IFNULL($6, priority), auto_complete=IFNULL($7, auto_complete),
+		deadline=IIF($8 IS NULL, deadline, NULLIF($8, '')), defer_until=IIF($9 IS NULL, defer_until, NULLIF($9, ''))
+		WHERE id=$10 AND ($11 = 0 OR version=$11) AND ` + activeTask
+
+	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
-		return fmt.Errorf("failed to update task")
+		return "", fmt.Errorf("failed to update task")
 	}
 
 	defer stmt.Close()
 
-	res, err := stmt.ExecContext(ctx, task.Date, task.Title, task.Comment, task.Repeat, id)
+	res, err := stmt.ExecContext(ctx, task.Date, task.Title, task.Comment, task.Repeat, projectID, priority, task.AutoComplete, task.Deadline, task.DeferUntil, id, task.Version)
 	if err != nil {
-		return fmt.Errorf("failed to update task")
+		return "", fmt.Errorf("failed to update task")
 	}
 
 	rows, err := res.RowsAffected()
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("task not found id: %s", id)
+		return "", versionConflict(ctx, tx, id, task.Version)
+	}
+
+	if err := moveTasks(ctx, tx, subtasks, projectID.String); err != nil {
+		return "", err
+	}
+
+	if task.Tags != nil {
+		if err := setTags(ctx, tx, id, task.Tags); err != nil {
+			return "", err
+		}
+	}
+
+	if task.Fields != nil {
+		if err := setFields(ctx, tx, id, task.Fields); err != nil {
+			return "", err
+		}
+	}
+
+	if task.Assignee != nil {
+		if err := setAssignee(ctx, tx, id, *task.Assignee); err != nil {
+			return "", err
+		}
+	}
+
+	if task.BlockedBy == nil {
+		return id, nil
 	}
 
-	return nil
+	return id, setDependencies(ctx, tx, id, task.BlockedBy)
 }
 
+// DeleteTask moves the task to the trash, it is removed for good by PurgeTrash.
 func (s *SqliteStorage) DeleteTask(ctx context.Context, id string) error {
-	query := `DELETE FROM scheduler WHERE id=$1`
+	_, err := s.withAudit(ctx, auditDelete, id, func(tx *sql.Tx) (string, error) {
+		return trashTask(ctx, tx, id)
+	})
 
-	stmt, err := s.db.PrepareContext(ctx, query)
+	return err
+}
+
+// trashTask moves a task to the trash, it runs inside auditTx.
+func trashTask(ctx context.Context, tx *sql.Tx, id string) (string, error) {
+	query := `UPDATE scheduler SET deleted_at=$1 WHERE id=$2 AND deleted_at IS NULL`
+
+	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
-		return fmt.Errorf("failed to delete task")
+		return "", fmt.Errorf("failed to delete task")
 	}
 
 	defer stmt.Close()
 
-	res, err := stmt.ExecContext(ctx, id)
+	res, err := stmt.ExecContext(ctx, time.Now().Unix(), id)
 	if err != nil {
-		return fmt.Errorf("failed to delete task")
+		return "", fmt.Errorf("failed to delete task")
 	}
 
 	rows, err := res.RowsAffected()
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("task not found id: %s", id)
+		return "", fmt.Errorf("task not found id: %s", id)
 	}
 
-	return nil
+	return id, nil
 }
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// subtaskParent checks that a new subtask can be added under its parent
+// and returns the parent's project, which the subtask always shares.
+// Subtasks are checklist items: they don't repeat and can't have
+// subtasks of their own.
+func subtaskParent(ctx context.Context, tx *sql.Tx, task Task) (string, error) {
+	if task.Repeat != "" {
+		return "", fmt.Errorf("subtasks can't repeat")
+	}
+
+	var (
+		projectID string
+		parentID  sql.NullString
+	)
+
+	query := `SELECT project_id, parent_id FROM scheduler WHERE id=$1 AND project_id IN ` + visibleProjects("$2") + ` AND ` + activeTask
+
+	err := tx.QueryRowContext(ctx, query, task.ParentID, userID(ctx)).Scan(&projectID, &parentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("parent task not found id: %s", task.ParentID)
+	} else if err != nil {
+		return "", err
+	}
+
+	if parentID.Valid {
+		return "", fmt.Errorf("subtasks can't have subtasks")
+	}
+
+	return projectID, nil
+}
+
+func checkRepeatAllowed(ctx context.Context, tx *sql.Tx, id string) error {
+	var parentID sql.NullString
+
+	err := tx.QueryRowContext(ctx, `SELECT parent_id FROM scheduler WHERE id=$1`, id).Scan(&parentID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if parentID.Valid {
+		return fmt.Errorf("subtasks can't repeat")
+	}
+
+	return nil
+}
+
+// resetSubtasks unchecks all completed subtasks of a task, it is used
+// when a repeating task moves to its next date.
+func resetSubtasks(ctx context.Context, tx *sql.Tx, parentID string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM scheduler
+		WHERE parent_id=$1 AND deleted_at IS NULL AND completed_at IS NOT NULL`, parentID)
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		_, err := auditTx(ctx, tx, auditUncomplete, id, func(tx *sql.Tx) (string, error) {
+			if _, err := tx.ExecContext(ctx, `UPDATE scheduler SET completed_at=NULL WHERE id=$1`, id); err != nil {
+				return "", fmt.Errorf("failed to reset subtask id: %s", id)
+			}
+
+			return id, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
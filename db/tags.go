@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const maxTagLength = 64
+
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type TagsResponse struct {
+	Tags []TagCount `json:"tags"`
+}
+
+// NormalizeTags lowercases tag names, drops a leading '#' and duplicates
+// and sorts the result. A nil slice stays nil, it means "leave the tags
+// as they are" on update.
+func NormalizeTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		name := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+
+		if name == "" {
+			return nil, fmt.Errorf("tag should not be empty")
+		}
+
+		if len([]rune(name)) > maxTagLength || strings.IndexFunc(name, func(r rune) bool {
+			return unicode.IsSpace(r) || r == ','
+		}) >= 0 {
+			return nil, fmt.Errorf("invalid tag %s", tag)
+		}
+
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+
+	sort.Strings(normalized)
+
+	return normalized, nil
+}
+
+// setTags replaces the tags of a task and drops tags no task uses anymore.
+func setTags(ctx context.Context, tx *sql.Tx, taskID string, tags []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM task_tags WHERE task_id=$1`, taskID); err != nil {
+		return fmt.Errorf("failed to set tags")
+	}
+
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, tag); err != nil {
+			return fmt.Errorf("failed to set tags")
+		}
+
+		query := `INSERT INTO task_tags (task_id, tag_id) SELECT $1, id FROM tags WHERE name=$2`
+		if _, err := tx.ExecContext(ctx, query, taskID, tag); err != nil {
+			return fmt.Errorf("failed to set tags")
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM task_tags)`); err != nil {
+		return fmt.Errorf("failed to set tags")
+	}
+
+	return nil
+}
+
+// GetTags lists the tags used in the projects the user can see with the
+// number of active tasks using them.
+func (s *SqliteStorage) GetTags(ctx context.Context) ([]TagCount, error) {
+	query := `SELECT t.name, COUNT(a.id) FROM tags t
+		JOIN task_tags tt ON tt.tag_id = t.id
+		JOIN scheduler s ON s.id = tt.task_id AND s.project_id IN ` + visibleProjects("$1") + `
+		LEFT JOIN (SELECT id FROM scheduler WHERE ` + activeTask + `) a ON a.id = s.id
+		GROUP BY t.id ORDER BY t.name`
+
+	rows, err := s.db.QueryContext(ctx, query, userID(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tags := []TagCount{}
+
+	for rows.Next() {
+		var tag TagCount
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
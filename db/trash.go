@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+func (s *SqliteStorage) GetTrash(ctx context.Context) ([]Task, error) {
+	query := `SELECT ` + taskColumns + `, '' FROM scheduler s WHERE s.deleted_at IS NOT NULL AND s.project_id IN ` + visibleProjects("$1") + ` ORDER BY s.deleted_at DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, userID(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTasks(rows)
+}
+
+func (s *SqliteStorage) RestoreTask(ctx context.Context, id string) error {
+	_, err := s.withAudit(ctx, auditRestore, id, func(tx *sql.Tx) (string, error) {
+		query := `UPDATE scheduler SET deleted_at=NULL WHERE id=$1 AND deleted_at IS NOT NULL`
+
+		res, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return "", fmt.Errorf("failed to restore task")
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return "", err
+		}
+
+		if rows == 0 {
+			return "", fmt.Errorf("task not found in trash id: %s", id)
+		}
+
+		return id, nil
+	})
+
+	return err
+}
+
+// PurgeTrash permanently removes tasks trashed before the given time and
+// returns how many were removed.
+func (s *SqliteStorage) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM scheduler WHERE deleted_at IS NOT NULL AND deleted_at < $1`
+
+	res, err := s.db.ExecContext(ctx, query, before.Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
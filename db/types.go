@@ -14,13 +14,92 @@ type Task struct {
 	Title   string `json:"title"`
 	Comment string `json:"comment"`
 	Repeat  string `json:"repeat"`
+	// Tags are sorted tag names. On update a missing tags field keeps
+	// the current tags, an empty list removes them.
+	Tags []string `json:"tags,omitempty"`
+	// ProjectID is the project the task belongs to. On update an empty
+	// value keeps the current project.
+	ProjectID string `json:"project_id,omitempty"`
+	// Priority is one of Priorities. On update an empty value keeps the
+	// current priority.
+	Priority string `json:"priority,omitempty"`
+	// ParentID is set for subtasks, it can't be changed after creation.
+	ParentID string `json:"parent_id,omitempty"`
+	// AutoComplete completes the task once all its subtasks are done.
+	// On update a missing value keeps the current setting.
+	AutoComplete *bool `json:"auto_complete,omitempty"`
+	// Progress counts the subtasks, it is only set for tasks with subtasks.
+	Progress *Progress `json:"progress,omitempty"`
+	// BlockedBy lists the ids of the tasks this one depends on. On update
+	// a missing field keeps the current dependencies, an empty list
+	// removes them.
+	BlockedBy []string `json:"blocked_by,omitempty"`
+	// Blocked is set while any of the blockers is not completed.
+	Blocked bool `json:"blocked,omitempty"`
+	// Deadline is the optional due date in lib.Layout, Date is when the
+	// task is planned. On update a missing deadline keeps the current one
+	// and an empty one removes it.
+	Deadline *string `json:"deadline,omitempty"`
+	// DeferUntil hides the task from listings before that day. It
+	// follows the update rules of Deadline.
+	DeferUntil *string `json:"defer_until,omitempty"`
+	// Assignee is the id of the user the task is assigned to, who must
+	// have access to the task's project. It follows the update rules of
+	// Deadline.
+	Assignee *string `json:"assignee,omitempty"`
+	// AssignedBy is the id of the user who set the assignee.
+	AssignedBy string `json:"assigned_by,omitempty"`
+	// Version grows with every change of the task, it is sent as the ETag
+	// of the task. On update a non-zero version must match the stored one.
+	Version int `json:"-"`
+	// Fields holds custom field values by field name. On update a missing
+	// fields object keeps the current values, otherwise it replaces them.
+	Fields map[string]any `json:"fields,omitempty"`
+	// Notes is the number of notes in the task's thread.
+	Notes   int    `json:"notes,omitempty"`
+	Snippet string `json:"snippet,omitempty"`
+	// DeletedAt is set for tasks in the trash.
+	DeletedAt string `json:"deleted_at,omitempty"`
+	// CompletedAt is set for archived one-off tasks.
+	CompletedAt string `json:"completed_at,omitempty"`
 }
 
 type CreateTaskRequest struct {
-	Date    string `json:"date"`
-	Title   string `json:"title"`
-	Comment string `json:"comment"`
-	Repeat  string `json:"repeat"`
+	Date    string   `json:"date"`
+	Title   string   `json:"title"`
+	Comment string   `json:"comment"`
+	Repeat  string   `json:"repeat"`
+	Tags    []string `json:"tags"`
+	// ProjectID defaults to the Inbox project.
+	ProjectID string `json:"project_id"`
+	Priority  string `json:"priority"`
+	ParentID  string `json:"parent_id"`
+	// AutoComplete completes the task once all its subtasks are done.
+	AutoComplete *bool          `json:"auto_complete"`
+	BlockedBy    []string       `json:"blocked_by"`
+	Fields       map[string]any `json:"fields"`
+	Deadline     string         `json:"deadline"`
+	DeferUntil   string         `json:"defer_until"`
+	// Assignee is a user id or "me".
+	Assignee string `json:"assignee"`
+}
+
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// Completion is one occurrence of a task marked as done.
+type Completion struct {
+	ID            string `json:"id"`
+	TaskID        string `json:"task_id"`
+	ScheduledDate string `json:"scheduled_date"`
+	CompletedAt   string `json:"completed_at"`
+	Note          string `json:"note"`
+}
+
+type CompletionsResponse struct {
+	Completions []Completion `json:"completions"`
 }
 
 type CreateTaskResponse struct {
@@ -31,9 +110,63 @@ type TasksResponse struct {
 	Tasks []Task `json:"tasks"`
 }
 
+// Priorities are the task priority names, stored as their index.
+var Priorities = []string{"none", "low", "medium", "high", "urgent"}
+
+// ParsePriority returns the stored value of a priority name, an empty
+// name means none.
+func ParsePriority(name string) (int, error) {
+	if name == "" {
+		return 0, nil
+	}
+
+	for i, p := range Priorities {
+		if p == name {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown priority %s", name)
+}
+
 var mapping map[byte]bool = map[byte]bool{'d': true, 'y': true, 'w': true, 'm': true}
 
-func NewTask(date, title, comment, repeat string) (Task, error) {
+// NewTask validates a task read from a request. The deadline is optional,
+// it is kept in lib.Layout and can't be before the task date.
+func NewTask(date, title, comment, repeat, deadline string) (Task, error) {
+	task, err := newTask(date, title, comment, repeat)
+	if err != nil || deadline == "" {
+		return task, err
+	}
+
+	if _, err := time.Parse(lib.Layout, deadline); err != nil {
+		return Task{}, fmt.Errorf("invalid deadline")
+	}
+
+	if deadline < task.Date {
+		return Task{}, fmt.Errorf("deadline can't be before the task date")
+	}
+
+	task.Deadline = &deadline
+
+	return task, nil
+}
+
+// NormalizeDeferDate checks a defer date. nil keeps the current date on
+// update and an empty one removes it.
+func NormalizeDeferDate(date *string) (*string, error) {
+	if date == nil || *date == "" {
+		return date, nil
+	}
+
+	if _, err := time.Parse(lib.Layout, *date); err != nil {
+		return nil, fmt.Errorf("invalid defer date")
+	}
+
+	return date, nil
+}
+
+func newTask(date, title, comment, repeat string) (Task, error) {
 	if len(repeat) != 0 && !mapping[repeat[0]] {
 		return Task{}, fmt.Errorf("unknown rule")
 	}
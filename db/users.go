@@ -0,0 +1,219 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const maxUserName = 64
+
+// User owns tasks and projects. Admins manage the other accounts.
+type User struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Admin bool   `json:"admin"`
+	// PasswordHash is empty for the built-in admin, who signs in with
+	// TODO_PASSWORD.
+	PasswordHash string `json:"-"`
+	CreatedAt    string `json:"created_at,omitempty"`
+}
+
+type UsersResponse struct {
+	Users []User `json:"users"`
+}
+
+// BuiltinAdmin is created by the migration that introduced users and owns
+// every task created before. Requests without a user in their context,
+// e.g. when authentication is off, act as this user.
+var BuiltinAdmin = User{ID: "1", Name: "admin", Admin: true}
+
+type userKey struct{}
+
+// WithUser returns a context carrying the authenticated user. Storage
+// methods only see the data of this user and record its name in the
+// audit log.
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFrom returns the user carried by the context, see WithUser.
+func UserFrom(ctx context.Context) User {
+	if user, ok := ctx.Value(userKey{}).(User); ok {
+		return user
+	}
+
+	return BuiltinAdmin
+}
+
+func userID(ctx context.Context) string {
+	return UserFrom(ctx).ID
+}
+
+// userTask restricts rows referencing a task by task_id to the tasks the
+// user bound to $2 can see.
+var userTask = `task_id IN (SELECT id FROM scheduler WHERE project_id IN ` + visibleProjects("$2") + `)`
+
+func NewUser(name string, admin bool) (User, error) {
+	name = strings.TrimSpace(name)
+
+	if name == "" {
+		return User{}, fmt.Errorf("user name should not be empty")
+	}
+
+	if len([]rune(name)) > maxUserName || strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+		return User{}, fmt.Errorf("invalid user name %s", name)
+	}
+
+	return User{Name: name, Admin: admin}, nil
+}
+
+const userColumns = `id, name, admin, password_hash, created_at`
+
+func scanUser(row scanner) (User, error) {
+	var (
+		u         User
+		createdAt sql.NullInt64
+	)
+
+	if err := row.Scan(&u.ID, &u.Name, &u.Admin, &u.PasswordHash, &createdAt); err != nil {
+		return User{}, err
+	}
+
+	u.CreatedAt = formatUnix(createdAt)
+
+	return u, nil
+}
+
+func (s *SqliteStorage) GetUsers(ctx context.Context) ([]User, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+userColumns+` FROM users ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	users := []User{}
+
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (s *SqliteStorage) GetUser(ctx context.Context, id string) (User, error) {
+	return s.findUser(ctx, `id=$1`, id)
+}
+
+func (s *SqliteStorage) GetUserByName(ctx context.Context, name string) (User, error) {
+	return s.findUser(ctx, `name=$1`, name)
+}
+
+func (s *SqliteStorage) findUser(ctx context.Context, cond, value string) (User, error) {
+	u, err := scanUser(s.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE `+cond, value))
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, fmt.Errorf("user not found %s", value)
+	} else if err != nil {
+		return User{}, err
+	}
+
+	return u, nil
+}
+
+// CreateUser adds an account together with its Inbox project.
+func (s *SqliteStorage) CreateUser(ctx context.Context, u User) (string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+
+	defer tx.Rollback()
+
+	now := time.Now().Unix()
+
+	query := `INSERT INTO users (name, admin, password_hash, created_at) VALUES ($1, $2, $3, $4) ON CONFLICT (name) DO NOTHING`
+
+	res, err := tx.ExecContext(ctx, query, u.Name, u.Admin, u.PasswordHash, now)
+	if err != nil {
+		return "", fmt.Errorf("failed to create user")
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+
+	if rows == 0 {
+		return "", fmt.Errorf("user already exists %s", u.Name)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+
+	query = `INSERT INTO projects (name, inbox, created_at, user_id) VALUES ('Inbox', 1, $1, $2)`
+
+	if _, err := tx.ExecContext(ctx, query, now, id); err != nil {
+		return "", fmt.Errorf("failed to create user")
+	}
+
+	return fmt.Sprint(id), tx.Commit()
+}
+
+// UpdateUserPassword replaces the password hash of a user, which also
+// invalidates the tokens issued before.
+func (s *SqliteStorage) UpdateUserPassword(ctx context.Context, id, passwordHash string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE users SET password_hash=$1 WHERE id=$2 AND id<>$3`, passwordHash, id, BuiltinAdmin.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update user")
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("user not found %s", id)
+	}
+
+	return nil
+}
+
+// DeleteUser removes an account with all its tasks and projects. The
+// built-in admin can't be deleted.
+func (s *SqliteStorage) DeleteUser(ctx context.Context, id string) error {
+	if id == BuiltinAdmin.ID {
+		return fmt.Errorf("built-in admin can't be deleted")
+	}
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM users WHERE id=$1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user")
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("user not found %s", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// VersionConflictError is returned by writes that expected another version
+// of the task than the stored one.
+type VersionConflictError struct {
+	ID      string
+	Version int
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("task id: %s was changed, current version is %d", e.ID, e.Version)
+}
+
+// versionConflict explains why a write that expected a version of an
+// active task matched no row.
+func versionConflict(ctx context.Context, tx *sql.Tx, id string, expected int) error {
+	var version int
+
+	err := tx.QueryRowContext(ctx, `SELECT version FROM scheduler WHERE id=$1 AND `+activeTask, id).Scan(&version)
+	if err != nil || expected == 0 || version == expected {
+		return fmt.Errorf("task not found id: %s", id)
+	}
+
+	return &VersionConflictError{ID: id, Version: version}
+}
+
+// checkVersion fails unless a non-zero expected version is the version of
+// the active task, for writes that don't match the version in their own
+// statement.
+func checkVersion(ctx context.Context, tx *sql.Tx, id string, expected int) error {
+	if expected == 0 {
+		return nil
+	}
+
+	var version int
+
+	err := tx.QueryRowContext(ctx, `SELECT version FROM scheduler WHERE id=$1 AND `+activeTask, id).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("task not found id: %s", id)
+	} else if err != nil {
+		return err
+	}
+
+	if version != expected {
+		return &VersionConflictError{ID: id, Version: version}
+	}
+
+	return nil
+}
@@ -10,6 +10,8 @@ require (
 
 require github.com/golang-jwt/jwt/v5 v5.2.1
 
+require golang.org/x/crypto v0.22.0
+
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/go-chi/chi v1.5.5
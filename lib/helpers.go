@@ -2,6 +2,7 @@ package lib
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -15,12 +16,34 @@ type ApiErr struct {
 	Error string `json:"error"`
 }
 
+// StatusError is an error answered with its own status instead of
+// 400 Bad Request.
+type StatusError struct {
+	Status int
+	Err    error
+}
+
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
 type ApiFunc func(w http.ResponseWriter, r *http.Request) error
 
 func MakeHTTP(fn ApiFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if err := fn(w, r); err != nil {
-			WriteJSON(w, http.StatusBadRequest, ApiErr{Error: err.Error()})
+			status := http.StatusBadRequest
+
+			var se *StatusError
+			if errors.As(err, &se) {
+				status = se.Status
+			}
+
+			WriteJSON(w, status, ApiErr{Error: err.Error()})
 		}
 	}
 }
@@ -1,32 +1,46 @@
 package repeattask
 
 import (
+	"fmt"
 	"time"
 )
 
-func UpdateDate(date, repeat string) (string, error) {
-	now := time.Date(time.Now().Year(), time.Now().Month(), time.Now().Day(), 0, 0, 0, 0, time.Now().UTC().Location())
+// NextDate returns the first date the rule gives for a task on date,
+// counting from now. Rules with several days pick the earliest one.
+func NextDate(now time.Time, date, repeat string) (string, error) {
+	if repeat == "" {
+		return "", fmt.Errorf("empty rule")
+	}
+
+	var (
+		next []string
+		err  error
+	)
 
 	switch repeat[0] {
 	case 'd':
-		next, err := RepeatD(now, date, repeat)
-		if err != nil {
-			return "", err
-		}
-		return next, nil
+		var d string
+		d, err = RepeatD(now, date, repeat)
+		next = []string{d}
 	case 'y':
-		next, err := RepeatY(now, date, repeat)
-		if err != nil {
-			return "", err
-		}
-		return next, nil
+		var d string
+		d, err = RepeatY(now, date, repeat)
+		next = []string{d}
 	case 'w':
-		next, err := RepeatW(now, date, repeat)
-		if err != nil {
-			return "", err
-		}
-		return next[0], nil
+		next, err = RepeatW(now, date, repeat)
+	case 'm':
+		next, err = RepeatM(now, date, repeat)
+	default:
+		return "", fmt.Errorf("unknown rule %s", repeat)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(next) == 0 {
+		return "", fmt.Errorf("no next date for rule %s", repeat)
 	}
 
-	return "", nil
+	return next[0], nil
 }
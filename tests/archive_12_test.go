@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func completedTask(t *testing.T, id, query string) map[string]string {
+	body, err := requestJSON("api/tasks/completed"+query, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]map[string]string
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+
+	for _, task := range m["tasks"] {
+		if task["id"] == id {
+			return task
+		}
+	}
+	return nil
+}
+
+func TestArchive(t *testing.T) {
+	now := time.Now()
+
+	id := addTask(t, task{
+		date:  now.Format(`20060102`),
+		title: "Сдать отчёт",
+	})
+
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+
+	done := completedTask(t, id, "?from="+now.Format(`02.01.2006`)+"&to="+now.Format(`02.01.2006`))
+	assert.NotNil(t, done)
+	assert.NotEmpty(t, done["completed_at"])
+	assert.Nil(t, completedTask(t, id, "?to="+now.AddDate(0, 0, -1).Format(`20060102`)))
+
+	ret, err = postJSON("api/task/uncomplete?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Nil(t, completedTask(t, id, ""))
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]string
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+	assert.Equal(t, id, m["id"])
+
+	ret, err = postJSON("api/task/uncomplete?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
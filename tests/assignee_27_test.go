@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssignee(t *testing.T) {
+	if len(Token) == 0 {
+		t.Skip("authentication is off")
+	}
+
+	bob, bobToken := createUser(t, "bob")
+	carol, carolToken := createUser(t, "carol")
+
+	ret, err := postJSON("api/project", map[string]any{"name": "Дежурства"}, http.MethodPost)
+	assert.NoError(t, err)
+	project := fmt.Sprint(ret["id"])
+
+	status, users := requestAs(t, Token, "api/users", nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, status)
+
+	names := map[string]string{}
+	for _, u := range users["users"].([]any) {
+		u := u.(map[string]any)
+		names[fmt.Sprint(u["id"])] = fmt.Sprint(u["name"])
+	}
+
+	ret, err = postJSON("api/project/members", map[string]any{
+		"project_id": project,
+		"name":       names[bob],
+		"role":       "editor",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+
+	// only users with access to the project can be assigned
+	ret, err = postJSON("api/task", map[string]any{
+		"title":      "Дежурство в субботу",
+		"project_id": project,
+		"assignee":   carol,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task", map[string]any{
+		"title":      "Дежурство в субботу",
+		"project_id": project,
+		"assignee":   bob,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	task := getTask(t, id)
+	assert.Equal(t, bob, task["assignee"])
+	assert.Equal(t, "1", task["assigned_by"])
+
+	assert.NotContains(t, taskIDs(t, "assignee=me"), id)
+
+	status, ret = requestAs(t, bobToken, "api/tasks?assignee=me", nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, ret["tasks"], 1)
+
+	ret, err = postJSON("api/project/members", map[string]any{
+		"project_id": project,
+		"name":       names[carol],
+		"role":       "editor",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+
+	status, _ = requestAs(t, carolToken, "api/task/done?id="+id, nil, http.MethodPost)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, _ = requestAs(t, bobToken, "api/task/done?id="+id, nil, http.MethodPost)
+	assert.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, completedTask(t, id, "")["completed_at"])
+
+	// "me" assigns the task to the author
+	status, ret = requestAs(t, carolToken, "api/task", map[string]any{
+		"title":      "Проверить сервер",
+		"project_id": project,
+		"assignee":   "me",
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusOK, status)
+	id = fmt.Sprint(ret["id"])
+
+	task = getTask(t, id)
+	assert.Equal(t, carol, task["assignee"])
+	assert.Equal(t, carol, task["assigned_by"])
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":       id,
+		"date":     time.Now().Format(`20060102`),
+		"title":    "Проверить сервер",
+		"assignee": "",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Empty(t, getTask(t, id)["assignee"])
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	ret, err = postJSON("api/project?mode=cascade&id="+project, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	for _, user := range []string{bob, carol} {
+		ret, err = postJSON("api/user?id="+user, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+}
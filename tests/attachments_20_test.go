@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sendWithToken(req *http.Request) (*http.Response, error) {
+	if len(Token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: Token})
+	}
+
+	return http.DefaultClient.Do(req)
+}
+
+func uploadFile(t *testing.T, id, name string, content []byte) (int, map[string]any) {
+	var buf bytes.Buffer
+
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("file", name)
+	assert.NoError(t, err)
+	_, err = fw.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, mw.Close())
+
+	req, err := http.NewRequest(http.MethodPost, getURL("api/task/attachments?id="+id), &buf)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	resp, err := sendWithToken(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var m map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&m))
+	return resp.StatusCode, m
+}
+
+func TestAttachments(t *testing.T) {
+	id := addTask(t, task{
+		title: "Чек из магазина",
+	})
+
+	content := []byte("молоко 89.90\nхлеб 45.00\n")
+	sum := sha256.Sum256(content)
+
+	status, a := uploadFile(t, id, "receipt.txt", content)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "receipt.txt", a["name"])
+	assert.EqualValues(t, len(content), a["size"])
+	assert.Contains(t, a["mime_type"], "text/plain")
+	assert.Equal(t, hex.EncodeToString(sum[:]), a["checksum"])
+	attachment := fmt.Sprint(a["id"])
+
+	body, err := requestJSON("api/task/attachments?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var list map[string][]map[string]any
+	assert.NoError(t, json.Unmarshal(body, &list))
+	assert.Len(t, list["attachments"], 1)
+
+	req, err := http.NewRequest(http.MethodGet, getURL("api/task/attachments?attachment="+attachment), nil)
+	assert.NoError(t, err)
+	resp, err := sendWithToken(req)
+	assert.NoError(t, err)
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, content, data)
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "receipt.txt")
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/plain")
+	assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"))
+
+	// active content is never served as a page
+	status, a = uploadFile(t, id, "page.html", []byte("<script>alert(1)</script>"))
+	assert.Equal(t, http.StatusOK, status)
+
+	req, err = http.NewRequest(http.MethodGet, getURL("api/task/attachments?attachment="+fmt.Sprint(a["id"])), nil)
+	assert.NoError(t, err)
+	resp, err = sendWithToken(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "application/octet-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"))
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "attachment")
+
+	status, a = uploadFile(t, id, "../../etc/passwd", []byte("x"))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "passwd", a["name"])
+
+	status, a = uploadFile(t, "999999999", "receipt.txt", content)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.NotEmpty(t, a["error"])
+
+	ret, err := postJSON("api/task/attachments?attachment="+attachment, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	ret, err = postJSON("api/task/attachments?attachment="+attachment, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func auditLog(t *testing.T, id string) []map[string]string {
+	body, err := requestJSON("api/audit?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]map[string]string
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+	return m["entries"]
+}
+
+func TestAudit(t *testing.T) {
+	now := time.Now()
+
+	id := addTask(t, task{
+		date:  now.Format(`20060102`),
+		title: "Перенести встречу",
+	})
+
+	entries := auditLog(t, id)
+	fields := map[string]string{}
+	for _, e := range entries {
+		assert.Equal(t, "create", e["action"])
+		assert.Empty(t, e["old_value"])
+		assert.NotEmpty(t, e["actor"])
+		fields[e["field"]] = e["new_value"]
+	}
+	assert.Equal(t, now.Format(`20060102`), fields["date"])
+	assert.Equal(t, "Перенести встречу", fields["title"])
+	created := len(entries)
+
+	tomorrow := now.AddDate(0, 0, 1).Format(`20060102`)
+	ret, err := postJSON("api/task", map[string]any{
+		"id":    id,
+		"date":  tomorrow,
+		"title": "Перенести встречу",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	entries = auditLog(t, id)
+	assert.Len(t, entries, created+1)
+	assert.Equal(t, "update", entries[0]["action"])
+	assert.Equal(t, "date", entries[0]["field"])
+	assert.Equal(t, now.Format(`20060102`), entries[0]["old_value"])
+	assert.Equal(t, tomorrow, entries[0]["new_value"])
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	entries = auditLog(t, id)
+	assert.Len(t, entries, created+2)
+	assert.Equal(t, "delete", entries[0]["action"])
+	assert.Equal(t, "deleted_at", entries[0]["field"])
+
+	// the limit is capped instead of rejected
+	for limit, want := range map[string]int{"1": 1, "1000000": 500} {
+		body, err := requestJSON("api/audit?limit="+limit, nil, http.MethodGet)
+		assert.NoError(t, err)
+
+		var m map[string][]map[string]string
+		assert.NoError(t, json.Unmarshal(body, &m))
+		assert.LessOrEqual(t, len(m["entries"]), want, limit)
+		assert.NotEmpty(t, m["entries"], limit)
+	}
+}
@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func backup(t *testing.T, token string) (int, []byte) {
+	req, err := http.NewRequest(http.MethodGet, getURL("api/admin/backup"), nil)
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "token", Value: token})
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp.StatusCode, body
+}
+
+func restore(t *testing.T, content []byte) (int, map[string]any) {
+	var buf bytes.Buffer
+
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("file", "scheduler.db")
+	assert.NoError(t, err)
+	_, err = fw.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, mw.Close())
+
+	req, err := http.NewRequest(http.MethodPost, getURL("api/admin/restore"), &buf)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	resp, err := sendWithToken(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var m map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&m))
+	return resp.StatusCode, m
+}
+
+func TestBackup(t *testing.T) {
+	if len(Token) == 0 {
+		t.Skip("authentication is off")
+	}
+
+	kept := addTask(t, task{title: "Задача из копии"})
+	orphaned := addTask(t, task{title: "Задача с историей", repeat: "d 2"})
+	ret, err := postJSON("api/task/done?id="+orphaned, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	status, snapshot := backup(t, Token)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, bytes.HasPrefix(snapshot, []byte("SQLite format 3\x00")))
+
+	path := filepath.Join(t.TempDir(), "backup.db")
+	assert.NoError(t, os.WriteFile(path, snapshot, 0o600))
+
+	copied, err := sqlx.Connect("sqlite3", path)
+	assert.NoError(t, err)
+	var title string
+	assert.NoError(t, copied.Get(&title, "SELECT title FROM scheduler WHERE id = ?", kept))
+	assert.Equal(t, "Задача из копии", title)
+
+	// a task deleted with foreign keys off leaves its history behind,
+	// the restore prunes it instead of failing
+	_, err = copied.Exec(`DELETE FROM scheduler WHERE id = ?`, orphaned)
+	assert.NoError(t, err)
+	assert.NoError(t, copied.Close())
+	snapshot, err = os.ReadFile(path)
+	assert.NoError(t, err)
+
+	_, token := createUser(t, "backup")
+	status, _ = backup(t, token)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	lost := addTask(t, task{title: "Задача после копии"})
+
+	for _, content := range [][]byte{[]byte("not a database"), {}} {
+		status, ret := restore(t, content)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.NotEmpty(t, ret["error"])
+	}
+	assert.Empty(t, getTask(t, lost)["error"])
+
+	status, ret = restore(t, snapshot)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, ret)
+
+	assert.Equal(t, "Задача из копии", getTask(t, kept)["title"])
+	assert.NotEmpty(t, getTask(t, lost)["error"])
+	assert.NotEmpty(t, getTask(t, orphaned)["error"])
+
+	ret, err = postJSON("api/task?id="+kept, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func runBatch(t *testing.T, mode string, ops ...map[string]any) (map[string]any, []string) {
+	ret, err := postJSON("api/tasks/batch", map[string]any{
+		"mode":       mode,
+		"operations": ops,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+
+	var statuses []string
+	results, _ := ret["results"].([]any)
+	for _, r := range results {
+		statuses = append(statuses, fmt.Sprint(r.(map[string]any)["status"]))
+	}
+	return ret, statuses
+}
+
+func TestBatch(t *testing.T) {
+	now := time.Now().Format(`20060102`)
+
+	first := addTask(t, task{title: "Купить билеты"})
+	second := addTask(t, task{title: "Забронировать отель"})
+	repeating := addTask(t, task{title: "Полить цветы", repeat: "d 2"})
+
+	ret, err := postJSON("api/project", map[string]any{"name": "Отпуск"}, http.MethodPost)
+	assert.NoError(t, err)
+	project := fmt.Sprint(ret["id"])
+
+	ops := []map[string]any{
+		{"op": "update", "id": first, "task": map[string]any{"date": now, "title": "Купить билеты на поезд"}},
+		{"op": "delete", "id": second},
+		{"op": "create", "task": map[string]any{"title": "Собрать чемодан"}},
+		{"op": "move", "id": first, "project_id": project},
+		{"op": "complete", "id": repeating},
+		{"op": "complete", "id": "999999"},
+	}
+
+	// one failed operation rolls back the whole batch
+	ret, statuses := runBatch(t, "", ops...)
+	assert.NotEmpty(t, ret["error"])
+	assert.Equal(t, []string{"rolled_back", "rolled_back", "rolled_back", "rolled_back", "rolled_back", "failed"}, statuses)
+	assert.Equal(t, "Купить билеты", getTask(t, first)["title"])
+	assert.Empty(t, getTask(t, second)["error"])
+	assert.Equal(t, now, getTask(t, repeating)["date"])
+
+	ret, statuses = runBatch(t, "best_effort", ops...)
+	assert.Empty(t, ret["error"])
+	assert.Equal(t, []string{"done", "done", "done", "done", "done", "failed"}, statuses)
+
+	updated := getTask(t, first)
+	assert.Equal(t, "Купить билеты на поезд", updated["title"])
+	assert.Equal(t, project, updated["project_id"])
+	assert.NotEmpty(t, getTask(t, second)["error"])
+	assert.Equal(t, time.Now().AddDate(0, 0, 2).Format(`20060102`), getTask(t, repeating)["date"])
+
+	created := fmt.Sprint(ret["results"].([]any)[2].(map[string]any)["id"])
+	assert.Equal(t, "Собрать чемодан", getTask(t, created)["title"])
+
+	// invalid operations fail without running
+	ret, statuses = runBatch(t, "best_effort",
+		map[string]any{"op": "rename", "id": first},
+		map[string]any{"op": "create", "task": map[string]any{"title": ""}},
+		map[string]any{"op": "delete"},
+		map[string]any{"op": "update", "id": first, "version": 1, "task": map[string]any{"date": now, "title": "Купить билеты"}},
+		map[string]any{"op": "delete", "id": created},
+	)
+	assert.Empty(t, ret["error"])
+	assert.Equal(t, []string{"failed", "failed", "failed", "failed", "done"}, statuses)
+
+	ret, err = postJSON("api/tasks/batch", map[string]any{"mode": "sometimes", "operations": ops}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+	assert.Nil(t, ret["results"])
+
+	for _, id := range []string{first, repeating} {
+		ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+
+	ret, err = postJSON("api/project?id="+project, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompleteConcurrently(t *testing.T) {
+	now := time.Now()
+
+	id := addTask(t, task{
+		title:  "Выпить воды",
+		repeat: "d 2",
+	})
+
+	// every request completes its own occurrence, none is skipped or
+	// counted twice
+	const n = 8
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+			assert.NoError(t, err)
+			assert.Empty(t, ret)
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, now.AddDate(0, 0, 2*n).Format(`20060102`), getTask(t, id)["date"])
+
+	history := taskHistory(t, id)
+	assert.Len(t, history, n)
+
+	dates := map[string]bool{}
+	for _, c := range history {
+		dates[c["scheduled_date"]] = true
+	}
+	assert.Len(t, dates, n)
+
+	ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
+
+func TestCompleteMonthly(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	// the nearest rule day after today
+	repeat, next := "m 1", time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	if now.Day() == 1 {
+		repeat, next = "m 2", today.AddDate(0, 0, 1)
+	}
+
+	ret, err := postJSON("api/task", map[string]any{
+		"title":    "Оплатить интернет",
+		"deadline": today.AddDate(0, 0, 3).Format(`20060102`),
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	id := fmt.Sprint(ret["id"])
+
+	_, err = db.Exec("UPDATE scheduler SET repeat = ? WHERE id = ?", repeat, id)
+	assert.NoError(t, err)
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	// the deadline keeps its distance from the new date
+	task := getTask(t, id)
+	assert.Equal(t, next.Format(`20060102`), task["date"])
+	assert.Equal(t, next.AddDate(0, 0, 3).Format(`20060102`), task["deadline"])
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
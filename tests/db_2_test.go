@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"database/sql"
 	"os"
 	"testing"
 	"time"
@@ -11,11 +12,23 @@ import (
 )
 
 type Task struct {
-	ID      int64  `db:"id"`
-	Date    string `db:"date"`
-	Title   string `db:"title"`
-	Comment string `db:"comment"`
-	Repeat  string `db:"repeat"`
+	ID          int64         `db:"id"`
+	Date        string        `db:"date"`
+	Title       string        `db:"title"`
+	Comment     string        `db:"comment"`
+	Repeat      string        `db:"repeat"`
+	DeletedAt   sql.NullInt64 `db:"deleted_at"`
+	CompletedAt sql.NullInt64 `db:"completed_at"`
+	ProjectID   sql.NullInt64 `db:"project_id"`
+	Priority    int           `db:"priority"`
+	ParentID    sql.NullInt64 `db:"parent_id"`
+	AutoDone    bool          `db:"auto_complete"`
+	Deadline    sql.NullInt64 `db:"deadline"`
+	DeferUntil  sql.NullInt64 `db:"defer_until"`
+	UserID      sql.NullInt64 `db:"user_id"`
+	AssigneeID  sql.NullInt64 `db:"assignee_id"`
+	AssignedBy  sql.NullInt64 `db:"assigned_by"`
+	Version     int           `db:"version"`
 }
 
 func count(db *sqlx.DB) (int, error) {
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadline(t *testing.T) {
+	now := time.Now()
+	day := func(days int) string {
+		return now.AddDate(0, 0, days).Format(`20060102`)
+	}
+
+	var ids []string
+	for _, deadline := range []string{day(2), day(20), ""} {
+		ret, err := postJSON("api/task", map[string]any{
+			"date":     day(1),
+			"title":    "Сдать отчёт",
+			"deadline": deadline,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"])
+		ids = append(ids, fmt.Sprint(ret["id"]))
+	}
+
+	assert.Equal(t, day(2), getTask(t, ids[0])["deadline"])
+	assert.Empty(t, getTask(t, ids[2])["deadline"])
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date":     day(5),
+		"title":    "Сдать отчёт",
+		"deadline": day(4),
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	found := taskIDs(t, "search=отчёт&due_within=3d")
+	assert.Contains(t, found, ids[0])
+	assert.NotContains(t, found, ids[1])
+	assert.NotContains(t, found, ids[2])
+
+	found = taskIDs(t, "search=отчёт+due_after:"+day(10))
+	assert.Equal(t, []any{ids[1]}, found)
+
+	found = taskIDs(t, "search=отчёт&sort=-deadline")
+	assert.Equal(t, []any{ids[1], ids[0], ids[2]}, found)
+
+	// moving the date past the kept deadline is refused
+	ret, err = postJSON("api/task", map[string]any{
+		"id":    ids[0],
+		"date":  day(3),
+		"title": "Сдать отчёт",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":       ids[0],
+		"date":     day(3),
+		"title":    "Сдать отчёт",
+		"deadline": "",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Empty(t, getTask(t, ids[0])["deadline"])
+
+	// a repeating task keeps the distance between date and deadline
+	ret, err = postJSON("api/task", map[string]any{
+		"id":       ids[1],
+		"date":     day(1),
+		"title":    "Сдать отчёт",
+		"repeat":   "d 7",
+		"deadline": day(20),
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	before := getTask(t, ids[1])
+	ret, err = postJSON("api/task/done?id="+ids[1], nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	after := getTask(t, ids[1])
+	start, _ := time.Parse(`20060102`, before["deadline"])
+	assert.Equal(t, start.AddDate(0, 0, 7).Format(`20060102`), after["deadline"])
+
+	// undo takes the deadline back along with the date
+	_, err = requestJSON("api/task/undo?id="+ids[1], nil, http.MethodPost)
+	assert.NoError(t, err)
+
+	undone := getTask(t, ids[1])
+	assert.Equal(t, before["date"], undone["date"])
+	assert.Equal(t, before["deadline"], undone["deadline"])
+
+	for _, id := range ids {
+		ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeferUntil(t *testing.T) {
+	now := time.Now()
+	day := func(days int) string {
+		return now.AddDate(0, 0, days).Format(`20060102`)
+	}
+
+	hidden := addTask(t, task{
+		title: "Продлить страховку",
+	})
+	visible := addTask(t, task{
+		title: "Продлить абонемент",
+	})
+
+	for id, date := range map[string]string{hidden: day(10), visible: day(0)} {
+		ret, err := postJSON("api/task", map[string]any{
+			"id":          id,
+			"title":       "Продлить",
+			"defer_until": date,
+		}, http.MethodPut)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+
+	found := taskIDs(t, "search=Продлить")
+	assert.Contains(t, found, visible)
+	assert.NotContains(t, found, hidden)
+
+	found = taskIDs(t, "search=Продлить&include_deferred=true")
+	assert.Contains(t, found, hidden)
+	assert.Equal(t, day(10), getTask(t, hidden)["defer_until"])
+
+	ret, err := postJSON("api/task", map[string]any{
+		"title":       "Продлить",
+		"defer_until": "завтра",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	// a repeating task moves its defer date along with the date
+	ret, err = postJSON("api/task", map[string]any{
+		"id":          hidden,
+		"date":        day(12),
+		"title":       "Продлить",
+		"repeat":      "d 30",
+		"defer_until": day(10),
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	before := getTask(t, hidden)
+	ret, err = postJSON("api/task/done?id="+hidden, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	after := getTask(t, hidden)
+	date, _ := time.Parse(`20060102`, before["date"])
+	assert.Equal(t, date.AddDate(0, 0, 30).Format(`20060102`), after["date"])
+	deferred, _ := time.Parse(`20060102`, before["defer_until"])
+	assert.Equal(t, deferred.AddDate(0, 0, 30).Format(`20060102`), after["defer_until"])
+
+	// undo takes the defer date back along with the date
+	_, err = requestJSON("api/task/undo?id="+hidden, nil, http.MethodPost)
+	assert.NoError(t, err)
+
+	undone := getTask(t, hidden)
+	assert.Equal(t, before["date"], undone["date"])
+	assert.Equal(t, before["defer_until"], undone["defer_until"])
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":          hidden,
+		"date":        before["date"],
+		"title":       "Продлить",
+		"defer_until": "",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Contains(t, taskIDs(t, "search=Продлить"), hidden)
+
+	for _, id := range []string{hidden, visible} {
+		ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func taskBlockers(t *testing.T, id string) (bool, []any) {
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string]any
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+
+	blocked, _ := m["blocked"].(bool)
+	blockers, _ := m["blocked_by"].([]any)
+	return blocked, blockers
+}
+
+func TestDependencies(t *testing.T) {
+	date := time.Now().AddDate(0, 0, 5).Format(`20060102`)
+
+	qa := addTask(t, task{
+		date:  date,
+		title: "Подпись QA",
+	})
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date":       date,
+		"title":      "Выкатить релиз",
+		"blocked_by": []string{qa},
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	deploy, _ := ret["id"].(string)
+
+	blocked, blockers := taskBlockers(t, deploy)
+	assert.True(t, blocked)
+	assert.Equal(t, []any{qa}, blockers)
+
+	ret, err = postJSON("api/task/done?id="+deploy, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	var ids []any
+	for _, task := range tasksWithTags(t, "blocked=true&on="+date) {
+		ids = append(ids, task["id"])
+	}
+	assert.Contains(t, ids, deploy)
+	assert.NotContains(t, ids, qa)
+
+	ids = nil
+	for _, task := range tasksWithTags(t, "search=is:ready+on:"+date) {
+		ids = append(ids, task["id"])
+	}
+	assert.Contains(t, ids, qa)
+	assert.NotContains(t, ids, deploy)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":         qa,
+		"date":       date,
+		"title":      "Подпись QA",
+		"blocked_by": []string{deploy},
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task/done?id="+qa, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	blocked, blockers = taskBlockers(t, deploy)
+	assert.False(t, blocked)
+	assert.Len(t, blockers, 1)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":         deploy,
+		"date":       date,
+		"title":      "Выкатить релиз",
+		"blocked_by": []string{},
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	_, blockers = taskBlockers(t, deploy)
+	assert.Empty(t, blockers)
+
+	ret, err = postJSON("api/task/done?id="+deploy, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func taskIDs(t *testing.T, query string) []any {
+	var ids []any
+	for _, task := range tasksWithTags(t, query) {
+		ids = append(ids, task["id"])
+	}
+	return ids
+}
+
+func TestFields(t *testing.T) {
+	var defs []string
+	for name, typ := range map[string]string{
+		"cost":    "number",
+		"ticket":  "string",
+		"paid_on": "date",
+		"paid":    "bool",
+	} {
+		ret, err := postJSON("api/field", map[string]any{
+			"name": name,
+			"type": typ,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"])
+		defs = append(defs, fmt.Sprint(ret["id"]))
+	}
+
+	ret, err := postJSON("api/field", map[string]any{
+		"name": "cost",
+		"type": "number",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	var ids []string
+	for _, fields := range []map[string]any{
+		{"cost": 150.5, "ticket": "OPS-1", "paid": true, "paid_on": "01.12.2026"},
+		{"cost": "40", "paid": false},
+	} {
+		ret, err := postJSON("api/task", map[string]any{
+			"title":  "Счёт поставщику",
+			"fields": fields,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"])
+		ids = append(ids, fmt.Sprint(ret["id"]))
+	}
+
+	for _, task := range tasksWithTags(t, "search=поставщику") {
+		if task["id"] != ids[0] {
+			continue
+		}
+		fields, _ := task["fields"].(map[string]any)
+		assert.EqualValues(t, 150.5, fields["cost"])
+		assert.Equal(t, "OPS-1", fields["ticket"])
+		assert.Equal(t, true, fields["paid"])
+		assert.Equal(t, "20261201", fields["paid_on"])
+	}
+
+	found := taskIDs(t, "search="+url.QueryEscape("поставщику field.cost>100"))
+	assert.Contains(t, found, ids[0])
+	assert.NotContains(t, found, ids[1])
+
+	found = taskIDs(t, "search=поставщику&field=paid:false")
+	assert.Equal(t, []any{ids[1]}, found)
+
+	found = taskIDs(t, "search=поставщику&field="+url.QueryEscape("paid_on>=30.11.2026")+"&field=ticket=OPS-1")
+	assert.Equal(t, []any{ids[0]}, found)
+
+	for _, query := range []string{"field.cost~1", "field.cost>=", "field.cost:"} {
+		ret, err := postJSON("api/tasks?search="+url.QueryEscape(query), nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], query)
+		assert.EqualValues(t, 0, ret["position"], query)
+	}
+
+	for _, params := range []string{"search=" + url.QueryEscape("field.customer=ACME"), "field=customer=ACME"} {
+		ret, err := postJSON("api/tasks?"+params, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Contains(t, ret["error"], "unknown field", params)
+	}
+
+	for _, fields := range []map[string]any{
+		{"cost": "дорого"},
+		{"customer": "ACME"},
+	} {
+		ret, err = postJSON("api/task", map[string]any{
+			"id":     ids[1],
+			"title":  "Счёт поставщику",
+			"fields": fields,
+		}, http.MethodPut)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"])
+	}
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":     ids[1],
+		"title":  "Счёт поставщику",
+		"fields": map[string]any{"cost": 200},
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Len(t, taskIDs(t, "search=поставщику&field=cost>100"), 2)
+
+	// definitions are shared, only an admin changes them
+	if len(Token) > 0 {
+		user, token := createUser(t, "dave")
+
+		status, _ := requestAs(t, token, "api/field", map[string]any{"name": "dave", "type": "string"}, http.MethodPost)
+		assert.Equal(t, http.StatusForbidden, status)
+		status, _ = requestAs(t, token, "api/field", map[string]any{"id": defs[0], "name": "price", "type": "number"}, http.MethodPut)
+		assert.Equal(t, http.StatusForbidden, status)
+		status, _ = requestAs(t, token, "api/field?id="+defs[0], nil, http.MethodDelete)
+		assert.Equal(t, http.StatusForbidden, status)
+
+		ret, err = postJSON("api/user?id="+user, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+
+	for _, id := range defs {
+		ret, err = postJSON("api/field?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+
+	for _, id := range ids {
+		ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+}
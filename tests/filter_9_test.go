@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// filterTasks lists the tasks mentioning word that match the parameters.
+func filterTasks(t *testing.T, word string, params url.Values) []string {
+	params.Set("search", word+" "+params.Get("search"))
+
+	body, err := requestJSON("api/tasks?"+params.Encode(), nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+
+	ids := []string{}
+	for _, task := range m["tasks"] {
+		ids = append(ids, fmt.Sprint(task["id"]))
+	}
+	return ids
+}
+
+func TestTaskFilter(t *testing.T) {
+	if !Search {
+		t.Skip("search is off")
+	}
+
+	now := time.Now()
+	day := func(days int) string {
+		return now.AddDate(0, 0, days).Format(`20060102`)
+	}
+
+	// a word no other task contains
+	word := "фильтр" + strconv.FormatInt(now.UnixNano(), 36)
+
+	var ids []string
+	for _, values := range []map[string]any{
+		{"title": "Купить краску", "comment": word, "date": day(3), "deadline": day(4)},
+		{"title": "Покрасить забор", "comment": word, "date": day(5), "deadline": day(10)},
+		{"title": "Убрать инструменты", "comment": word, "date": day(7)},
+	} {
+		ret, err := postJSON("api/task", values, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"])
+		ids = append(ids, fmt.Sprint(ret["id"]))
+	}
+
+	for _, c := range []struct {
+		params url.Values
+		want   []string
+	}{
+		{url.Values{"from": {day(4)}}, ids[1:]},
+		{url.Values{"from": {day(4)}, "to": {day(6)}}, ids[1:2]},
+		// the parameters narrow the dates of the query, never widen them
+		{url.Values{"search": {"after:" + day(3)}, "from": {day(2)}}, ids[1:]},
+		{url.Values{"search": {"before:" + day(7)}, "to": {day(9)}}, ids[:2]},
+		{url.Values{"deadline_to": {day(5)}}, ids[:1]},
+		{url.Values{"deadline_from": {day(5)}}, ids[1:2]},
+		{url.Values{"due_within": {"6d"}}, ids[:1]},
+		{url.Values{"search": {"due_before:" + day(5)}, "due_within": {"2w"}}, ids[:1]},
+		// the limit is capped instead of rejected
+		{url.Values{"limit": {"1000000"}}, ids},
+	} {
+		assert.ElementsMatch(t, c.want, filterTasks(t, word, c.params), c.params)
+	}
+
+	assert.Len(t, filterTasks(t, word, url.Values{"limit": {"1"}}), 1)
+
+	for _, params := range []string{"from=tomorrow", "deadline_to=32.01.2026", "due_within=3", "limit=-1", "limit=ten"} {
+		ret, err := postJSON("api/tasks?"+params, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], params)
+	}
+
+	for _, id := range ids {
+		ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+}
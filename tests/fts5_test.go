@@ -0,0 +1,18 @@
+//go:build !sqlite_fts5
+
+package tests
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// The scheduler table has full-text search triggers, so every write to it
+// needs the FTS5 module that go-sqlite3 only builds with the sqlite_fts5
+// tag. Without it the tests writing to the database would fail with
+// "no such module: fts5", so the run stops with a clear message instead.
+func TestMain(m *testing.M) {
+	fmt.Fprintln(os.Stderr, "tests need the sqlite_fts5 build tag: make test or go test -tags sqlite_fts5 ./tests")
+	os.Exit(1)
+}
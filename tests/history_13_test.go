@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func taskHistory(t *testing.T, id string) []map[string]string {
+	body, err := requestJSON("api/task/history?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]map[string]string
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+	return m["completions"]
+}
+
+func TestHistory(t *testing.T) {
+	now := time.Now()
+
+	id := addTask(t, task{
+		title:  "Полить цветы",
+		repeat: "d 2",
+	})
+	assert.Empty(t, taskHistory(t, id))
+
+	ret, err := postJSON("api/task/done?id="+id+"&note=полил", nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	history := taskHistory(t, id)
+	assert.Len(t, history, 2)
+	assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), history[0]["scheduled_date"])
+	assert.Equal(t, now.Format(`20060102`), history[1]["scheduled_date"])
+	assert.Equal(t, "полил", history[1]["note"])
+
+	body, err := requestJSON("api/task/undo?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	var m map[string]string
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), m["date"])
+	assert.Len(t, taskHistory(t, id), 1)
+
+	ret, err = postJSON("api/task/undo?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	ret, err = postJSON("api/task/undo?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
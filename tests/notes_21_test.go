@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func taskNotes(t *testing.T, id string) []map[string]string {
+	body, err := requestJSON("api/task/notes?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]map[string]string
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+	return m["notes"]
+}
+
+func TestNotes(t *testing.T) {
+	id := addTask(t, task{
+		title:   "Ремонт на кухне",
+		comment: "Описание задачи",
+	})
+
+	var notes []string
+	for _, text := range []string{"Позвонил мастеру", "Купить плитку"} {
+		ret, err := postJSON("api/task/notes", map[string]any{
+			"task_id": id,
+			"text":    text,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"])
+		notes = append(notes, fmt.Sprint(ret["id"]))
+	}
+
+	thread := taskNotes(t, id)
+	assert.Len(t, thread, 2)
+	assert.Equal(t, "Позвонил мастеру", thread[0]["text"])
+	assert.NotEmpty(t, thread[0]["author"])
+	assert.NotEmpty(t, thread[0]["created_at"])
+	assert.Empty(t, thread[0]["updated_at"])
+
+	var count any
+	for _, task := range tasksWithTags(t, "search=кухне") {
+		if task["id"] == id {
+			count = task["notes"]
+			assert.Equal(t, "Описание задачи", task["comment"])
+		}
+	}
+	assert.EqualValues(t, 2, count)
+
+	ret, err := postJSON("api/task/notes", map[string]any{
+		"id":   notes[1],
+		"text": "Купить плитку и клей",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	thread = taskNotes(t, id)
+	assert.Equal(t, "Купить плитку и клей", thread[1]["text"])
+	assert.NotEmpty(t, thread[1]["updated_at"])
+
+	ret, err = postJSON("api/task/notes", map[string]any{
+		"task_id": id,
+		"text":    "   ",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	for _, note := range notes {
+		ret, err = postJSON("api/task/notes?note="+note, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+	assert.Empty(t, taskNotes(t, id))
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
+
+func TestNoteAuthor(t *testing.T) {
+	if len(Token) == 0 {
+		t.Skip("authentication is off")
+	}
+
+	ret, err := postJSON("api/project", map[string]any{"name": fmt.Sprintf("Заметки %d", time.Now().UnixNano())}, http.MethodPost)
+	assert.NoError(t, err)
+	project := fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/task", map[string]any{"title": "Обсудить смету", "project_id": project}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	// a user registered under the name of a deleted one doesn't get their notes
+	name := fmt.Sprintf("erin%d", time.Now().UnixNano())
+
+	var note string
+	for i := 0; i < 2; i++ {
+		ret, err = postJSON("api/user", map[string]any{"name": name, "password": "secret"}, http.MethodPost)
+		assert.NoError(t, err)
+		user := fmt.Sprint(ret["id"])
+
+		ret, err = postJSON("api/signin", map[string]any{"name": name, "password": "secret"}, http.MethodPost)
+		assert.NoError(t, err)
+		token := fmt.Sprint(ret["token"])
+
+		ret, err = postJSON("api/project/members", map[string]any{"project_id": project, "name": name, "role": "editor"}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"])
+
+		if note == "" {
+			status, ret := requestAs(t, token, "api/task/notes", map[string]any{"task_id": id, "text": "Дороговато"}, http.MethodPost)
+			assert.Equal(t, http.StatusOK, status)
+			note = fmt.Sprint(ret["id"])
+		} else {
+			status, _ := requestAs(t, token, "api/task/notes", map[string]any{"id": note, "text": "Нормально"}, http.MethodPut)
+			assert.Equal(t, http.StatusBadRequest, status)
+			status, _ = requestAs(t, token, "api/task/notes?note="+note, nil, http.MethodDelete)
+			assert.Equal(t, http.StatusBadRequest, status)
+			assert.Equal(t, "Дороговато", taskNotes(t, id)[0]["text"])
+		}
+
+		ret, err = postJSON("api/user?id="+user, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+
+	ret, err = postJSON("api/project?mode=cascade&id="+project, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
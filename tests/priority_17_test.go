@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriority(t *testing.T) {
+	date := time.Now().AddDate(0, 1, 0).Format(`20060102`)
+
+	// a project of its own keeps other tasks on the same day out
+	ret, err := postJSON("api/project", map[string]any{"name": fmt.Sprintf("Приоритеты %d", time.Now().UnixNano())}, http.MethodPost)
+	assert.NoError(t, err)
+	project := fmt.Sprint(ret["id"])
+
+	var ids []string
+	for _, v := range []struct {
+		title    string
+		priority string
+	}{
+		{"Б рутина", ""},
+		{"А срочно", "urgent"},
+		{"В важно", "high"},
+		{"Г тоже рутина", "low"},
+	} {
+		ret, err := postJSON("api/task", map[string]any{
+			"date":       date,
+			"title":      v.title,
+			"priority":   v.priority,
+			"project_id": project,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		ids = append(ids, fmt.Sprint(ret["id"]))
+	}
+
+	assert.Equal(t, "none", getTask(t, ids[0])["priority"])
+	assert.Equal(t, "urgent", getTask(t, ids[1])["priority"])
+
+	tasks := getTasks(t, "on:"+date+"&sort=date,-priority,title&project="+project)
+	assert.Len(t, tasks, 4)
+	var order []string
+	for _, task := range tasks {
+		order = append(order, task["id"])
+	}
+	assert.Equal(t, []string{ids[1], ids[2], ids[3], ids[0]}, order)
+
+	tasks = getTasks(t, "on:"+date+"&sort=-title&project="+project)
+	assert.Equal(t, ids[3], tasks[0]["id"])
+
+	tasks = getTasks(t, "priority:high&project="+project)
+	assert.Len(t, tasks, 1)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":    ids[1],
+		"date":  date,
+		"title": "А срочно",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Equal(t, "urgent", getTask(t, ids[1])["priority"])
+
+	body, err := requestJSON("api/tasks?sort=date,-password", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "error")
+
+	ret, err = postJSON("api/task", map[string]any{
+		"title":    "Непонятно",
+		"priority": "critical",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	for _, id := range ids {
+		ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+
+	ret, err = postJSON("api/project?id="+project, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
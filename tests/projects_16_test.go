@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type projectsResponse struct {
+	Projects []struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Inbox bool   `json:"inbox"`
+		Count int    `json:"count"`
+	} `json:"projects"`
+}
+
+func inboxID(t *testing.T) string {
+	body, err := requestJSON("api/projects", nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var resp projectsResponse
+	err = json.Unmarshal(body, &resp)
+	assert.NoError(t, err)
+
+	for _, p := range resp.Projects {
+		if p.Inbox {
+			return p.ID
+		}
+	}
+	t.Fatal("Inbox не найден")
+	return ""
+}
+
+func getTask(t *testing.T, id string) map[string]string {
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string]string
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+	return m
+}
+
+func TestProjects(t *testing.T) {
+	inbox := inboxID(t)
+
+	id := addTask(t, task{title: "Задача без проекта"})
+	assert.Equal(t, inbox, getTask(t, id)["project_id"])
+
+	ret, err := postJSON("api/project", map[string]any{"name": "Дом"}, http.MethodPost)
+	assert.NoError(t, err)
+	project := fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/task", map[string]any{
+		"title":      "Починить кран",
+		"project_id": project,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	inProject := fmt.Sprint(ret["id"])
+
+	tasks := getTasks(t, "project:Дом")
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, inProject, tasks[0]["id"])
+
+	ret, err = postJSON("api/project", map[string]any{"id": project, "name": "Квартира"}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	ret, err = postJSON("api/project?id="+project, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Equal(t, inbox, getTask(t, inProject)["project_id"])
+
+	ret, err = postJSON("api/project?id="+inbox, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/project", map[string]any{"name": "Временный"}, http.MethodPost)
+	assert.NoError(t, err)
+	project = fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":         id,
+		"title":      "Задача без проекта",
+		"project_id": project,
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	ret, err = postJSON("api/project?mode=cascade&id="+project, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+	assert.True(t, inTrash(t, id))
+
+	ret, err = postJSON("api/task?id="+inProject, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
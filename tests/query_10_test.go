@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchQuery(t *testing.T) {
+	if !Search {
+		t.Skip("search is off")
+	}
+
+	now := time.Now()
+	day := func(days int) string {
+		return now.AddDate(0, 0, days).Format(`20060102`)
+	}
+
+	// a word no other task contains
+	word := "запрос" + strconv.FormatInt(now.UnixNano(), 36)
+
+	var ids []string
+	for _, values := range []map[string]any{
+		{"title": "Отчёт за март", "comment": "черновик " + word, "date": day(2), "tags": []string{"работа"}},
+		{"title": "Отчёт за год", "comment": "годовой отчёт " + word, "date": day(4)},
+		{"title": "Черновик письма", "comment": "март " + word, "date": day(6)},
+	} {
+		ret, err := postJSON("api/task", values, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"])
+		ids = append(ids, fmt.Sprint(ret["id"]))
+	}
+
+	march, year, letter := ids[0], ids[1], ids[2]
+
+	for _, c := range []struct {
+		query string
+		want  []string
+	}{
+		{"отчёт", []string{march, year}},
+		{"отч", []string{march, year}},
+		{"отчёт -черновик", []string{year}},
+		{"-отчёт", []string{letter}},
+		{`"за март"`, []string{march}},
+		{`"март за"`, []string{}},
+		{`-"за март"`, []string{year, letter}},
+		{"title:черновик", []string{letter}},
+		{"comment:черновик", []string{march}},
+		{`comment:"годовой отчёт"`, []string{year}},
+		{"march title:март", []string{}},
+		{"after:" + day(2) + " before:" + day(6), []string{year}},
+		{"after:" + day(2) + " after:" + day(4), []string{letter}},
+		{"on:" + now.AddDate(0, 0, 4).Format(`02.01.2006`), []string{year}},
+		{now.AddDate(0, 0, 6).Format(`02.01.2006`), []string{letter}},
+		{"#работа", []string{march}},
+		{"-tag:работа март", []string{letter}},
+	} {
+		assert.ElementsMatch(t, c.want, filterTasks(t, word, url.Values{"search": {c.query}}), c.query)
+	}
+
+	for _, c := range []struct {
+		query    string
+		position int
+	}{
+		{`отчёт "за март`, 6},
+		{`отчёт ""`, 6},
+		{"before:завтра", 7},
+		{"title:", 6},
+		{"- отчёт", 0},
+		{"отчёт -before:" + day(1), 6},
+		{"repeat:sometimes", 7},
+		{`от"чёт`, 2},
+	} {
+		ret, err := postJSON("api/tasks?search="+url.QueryEscape(c.query), nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], c.query)
+		assert.EqualValues(t, c.position, ret["position"], c.query)
+	}
+
+	for _, id := range ids {
+		ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func searchTasks(t *testing.T, query string) []map[string]any {
+	body, err := requestJSON("api/tasks?search="+url.QueryEscape(query), nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m["tasks"]
+}
+
+func searchIDs(tasks []map[string]any) []string {
+	ids := []string{}
+	for _, task := range tasks {
+		ids = append(ids, fmt.Sprint(task["id"]))
+	}
+	return ids
+}
+
+func TestSearch(t *testing.T) {
+	if !Search {
+		t.Skip("search is off")
+	}
+
+	// a word no other task contains
+	word := "квокка" + strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	ret, err := postJSON("api/task", map[string]any{"title": "План", "comment": word + " отчёт"}, http.MethodPost)
+	assert.NoError(t, err)
+	inComment := fmt.Sprint(ret["id"])
+
+	inTitle := addTask(t, task{title: "Отчёт " + word})
+	markup := addTask(t, task{title: "<img src=x onerror=alert(1)> " + word})
+
+	// matches in the title rank above matches in the comment
+	ids := searchIDs(searchTasks(t, word))
+	assert.Len(t, ids, 3)
+	assert.Equal(t, inComment, ids[2])
+
+	// partially typed words match as a prefix
+	assert.ElementsMatch(t, ids, searchIDs(searchTasks(t, word[:len(word)-3])))
+
+	// a phrase keeps the order of the words
+	assert.Equal(t, []string{inTitle}, searchIDs(searchTasks(t, `"отчёт `+word+`"`)))
+	assert.Equal(t, []string{inComment}, searchIDs(searchTasks(t, `"`+word+` отчёт"`)))
+
+	for _, task := range searchTasks(t, "title:"+word) {
+		if fmt.Sprint(task["id"]) != markup {
+			continue
+		}
+
+		snippet := fmt.Sprint(task["snippet"])
+		assert.Contains(t, snippet, "&lt;img src=x onerror=alert(1)&gt;")
+		assert.Contains(t, snippet, "<mark>"+word+"</mark>")
+		assert.NotContains(t, snippet, "<img")
+	}
+
+	for _, id := range []string{inComment, inTitle, markup} {
+		ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+}
@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createUser registers a user and signs it in.
+func createUser(t *testing.T, name string) (string, string) {
+	name = fmt.Sprintf("%s%d", name, time.Now().UnixNano())
+
+	ret, err := postJSON("api/user", map[string]any{
+		"name":     name,
+		"password": "secret",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	id := fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/signin", map[string]any{
+		"name":     name,
+		"password": "secret",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["token"])
+
+	return id, fmt.Sprint(ret["token"])
+}
+
+func TestSharing(t *testing.T) {
+	if len(Token) == 0 {
+		t.Skip("authentication is off")
+	}
+
+	bob, bobToken := createUser(t, "bob")
+	carol, carolToken := createUser(t, "carol")
+
+	ret, err := postJSON("api/project", map[string]any{"name": "Покупки"}, http.MethodPost)
+	assert.NoError(t, err)
+	project := fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/task", map[string]any{
+		"title":      "Купить молоко",
+		"project_id": project,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	status, _ := requestAs(t, bobToken, "api/task?id="+id, nil, http.MethodGet)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	for _, m := range []map[string]any{
+		{"project_id": project, "name": "nobody", "role": "viewer"},
+		{"project_id": project, "name": "admin", "role": "viewer"},
+		{"project_id": inboxID(t), "name": "bob", "role": "viewer"},
+		{"project_id": project, "name": "bob", "role": "owner"},
+	} {
+		ret, err = postJSON("api/project/members", m, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], m)
+	}
+
+	for user, role := range map[string]string{bob: "viewer", carol: "editor"} {
+		status, ret := requestAs(t, Token, "api/users", nil, http.MethodGet)
+		assert.Equal(t, http.StatusOK, status)
+
+		var name string
+		for _, u := range ret["users"].([]any) {
+			if u := u.(map[string]any); u["id"] == user {
+				name = fmt.Sprint(u["name"])
+			}
+		}
+
+		ret, err = postJSON("api/project/members", map[string]any{
+			"project_id": project,
+			"name":       name,
+			"role":       role,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Equal(t, role, ret["role"])
+	}
+
+	status, ret = requestAs(t, bobToken, "api/project/members?id="+project, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, ret["members"], 3)
+
+	status, ret = requestAs(t, bobToken, "api/projects", nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, ret["projects"], map[string]any{
+		"id": project, "name": "Покупки", "inbox": false, "count": float64(1), "role": "viewer",
+	})
+
+	// viewers only read
+	status, ret = requestAs(t, bobToken, "api/tasks?project="+project, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, ret["tasks"], 1)
+
+	status, _ = requestAs(t, bobToken, "api/task", map[string]any{
+		"id":    id,
+		"date":  time.Now().Format(`20060102`),
+		"title": "Купить кефир",
+	}, http.MethodPut)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, _ = requestAs(t, bobToken, "api/task/done?id="+id, nil, http.MethodPost)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	// a shift only picks tasks of projects the user can edit
+	today := time.Now().Format(`20060102`)
+	status, ret = requestAs(t, bobToken, "api/tasks/shift", map[string]any{"from": today, "to": today, "days": 1, "dry_run": true}, http.MethodPost)
+	assert.Equal(t, http.StatusOK, status)
+	for _, task := range ret["tasks"].([]any) {
+		assert.NotEqual(t, id, task.(map[string]any)["id"])
+	}
+
+	status, _ = requestAs(t, bobToken, "api/project/members", map[string]any{
+		"project_id": project,
+		"user_id":    carol,
+		"role":       "viewer",
+	}, http.MethodPut)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	// editors change tasks but not the project
+	status, _ = requestAs(t, carolToken, "api/task", map[string]any{
+		"id":    id,
+		"date":  time.Now().Format(`20060102`),
+		"title": "Купить кефир",
+	}, http.MethodPut)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Купить кефир", getTask(t, id)["title"])
+
+	status, ret = requestAs(t, carolToken, "api/task", map[string]any{
+		"title":      "Купить хлеб",
+		"project_id": project,
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Купить хлеб", getTask(t, fmt.Sprint(ret["id"]))["title"])
+
+	status, _ = requestAs(t, carolToken, "api/project", map[string]any{
+		"id":   project,
+		"name": "Мои покупки",
+	}, http.MethodPut)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	// a member who lost the editor role can't touch their old notes
+	status, ret = requestAs(t, carolToken, "api/task/notes", map[string]any{
+		"task_id": id,
+		"text":    "Взять обезжиренный",
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusOK, status)
+	note := fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/project/members", map[string]any{
+		"project_id": project,
+		"user_id":    carol,
+		"role":       "viewer",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	status, _ = requestAs(t, carolToken, "api/task/notes", map[string]any{
+		"id":   note,
+		"text": "Взять любой",
+	}, http.MethodPut)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, _ = requestAs(t, carolToken, "api/task/notes?note="+note, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	ret, err = postJSON("api/project/members", map[string]any{
+		"project_id": project,
+		"user_id":    bob,
+		"role":       "editor",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	status, _ = requestAs(t, bobToken, "api/task/done?id="+id, nil, http.MethodPost)
+	assert.Equal(t, http.StatusOK, status)
+
+	// members leave on their own, the owner revokes anyone
+	status, _ = requestAs(t, carolToken, "api/project/members?id="+project+"&user="+carol, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusOK, status)
+
+	ret, err = postJSON("api/project/members?id="+project+"&user="+bob, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	for _, token := range []string{bobToken, carolToken} {
+		status, ret = requestAs(t, token, "api/tasks?project="+project, nil, http.MethodGet)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Empty(t, ret["tasks"])
+	}
+
+	ret, err = postJSON("api/project?mode=cascade&id="+project, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	for _, user := range []string{bob, carol} {
+		ret, err = postJSON("api/user?id="+user, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+}
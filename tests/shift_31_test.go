@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func shiftTasks(t *testing.T, values map[string]any) (map[string]any, map[string]string) {
+	ret, err := postJSON("api/tasks/shift", values, http.MethodPost)
+	assert.NoError(t, err)
+
+	moved := map[string]string{}
+	tasks, _ := ret["tasks"].([]any)
+	for _, task := range tasks {
+		m := task.(map[string]any)
+		moved[fmt.Sprint(m["id"])] = fmt.Sprint(m["new_date"])
+	}
+	return ret, moved
+}
+
+func TestShiftTasks(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	day := func(days int) string {
+		return now.AddDate(0, 0, days).Format(`20060102`)
+	}
+
+	once := addTask(t, task{title: "Сдать отчёт"})
+	weekly := addTask(t, task{title: "Убраться дома", repeat: "d 7"})
+	earlier := addTask(t, task{title: "Позвонить в банк"})
+
+	// tasks left overdue during a vacation
+	for id, date := range map[string]string{once: day(-10), weekly: day(-10), earlier: day(-30)} {
+		_, err := db.Exec("UPDATE scheduler SET date = ? WHERE id = ?", date, id)
+		assert.NoError(t, err)
+	}
+
+	_, err := db.Exec("UPDATE scheduler SET deadline = ? WHERE id = ?", day(-8), once)
+	assert.NoError(t, err)
+
+	vacation := map[string]any{
+		"from":    day(-12),
+		"to":      day(-8),
+		"today":   true,
+		"dry_run": true,
+	}
+
+	ret, moved := shiftTasks(t, vacation)
+	assert.Empty(t, ret["error"])
+	assert.Equal(t, true, ret["dry_run"])
+	assert.Equal(t, day(0), moved[once])
+	// a repeating task moves to its next occurrence instead
+	assert.Equal(t, day(4), moved[weekly])
+	assert.NotContains(t, moved, earlier)
+	assert.Equal(t, day(-10), getTask(t, once)["date"])
+
+	vacation["dry_run"] = false
+	ret, moved = shiftTasks(t, vacation)
+	assert.Empty(t, ret["error"])
+	assert.Len(t, moved, 2)
+
+	task := getTask(t, once)
+	assert.Equal(t, day(0), task["date"])
+	assert.Equal(t, day(2), task["deadline"])
+	assert.Equal(t, day(4), getTask(t, weekly)["date"])
+	assert.Equal(t, day(-30), getTask(t, earlier)["date"])
+
+	_, moved = shiftTasks(t, map[string]any{"from": day(-30), "to": day(-30), "days": 3})
+	assert.Equal(t, map[string]string{earlier: day(-27)}, moved)
+
+	// without from the range has no lower bound
+	_, moved = shiftTasks(t, map[string]any{"to": day(-27), "days": 1, "dry_run": true})
+	assert.Equal(t, day(-26), moved[earlier])
+
+	for _, values := range []map[string]any{
+		{"from": day(-30), "to": day(-1)},
+		{"from": day(-30), "to": day(-1), "days": -2},
+		{"from": day(-1), "to": day(-30), "days": 2},
+		{"to": day(1), "today": true},
+		{"from": "yesterday", "to": day(-1), "today": true},
+		{"from": day(-30), "today": true},
+	} {
+		ret, _ = shiftTasks(t, values)
+		assert.NotEmpty(t, ret["error"], values)
+	}
+
+	for _, id := range []string{once, weekly, earlier} {
+		ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+}
@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parentTask(t *testing.T, id string) (map[string]any, map[string]any) {
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string]any
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+
+	progress, _ := m["progress"].(map[string]any)
+	return m, progress
+}
+
+func TestSubtasks(t *testing.T) {
+	date := time.Now().AddDate(0, 0, 3).Format(`20060102`)
+
+	parent := addTask(t, task{
+		date:  date,
+		title: "Собрать вещи",
+	})
+
+	var items []string
+	for _, title := range []string{"Паспорт", "Зарядка", "Зубная щётка"} {
+		ret, err := postJSON("api/task", map[string]any{
+			"title":     title,
+			"parent_id": parent,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"])
+		items = append(items, fmt.Sprint(ret["id"]))
+	}
+
+	_, progress := parentTask(t, parent)
+	assert.EqualValues(t, 0, progress["done"])
+	assert.EqualValues(t, 3, progress["total"])
+
+	for _, task := range tasksWithTags(t, "") {
+		assert.NotContains(t, items, task["id"])
+	}
+	assert.Len(t, tasksWithTags(t, "parent="+parent), 3)
+
+	ret, err := postJSON("api/task", map[string]any{
+		"title":     "Вложенная",
+		"parent_id": items[0],
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task", map[string]any{
+		"title":     "Повторная",
+		"parent_id": parent,
+		"repeat":    "d 1",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task/done?id="+items[0], nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	_, progress = parentTask(t, parent)
+	assert.EqualValues(t, 1, progress["done"])
+	assert.Len(t, tasksWithTags(t, "parent="+parent), 3)
+
+	// a recurring parent unchecks its list when it moves to the next date
+	ret, err = postJSON("api/task", map[string]any{
+		"id":            parent,
+		"date":          date,
+		"title":         "Собрать вещи",
+		"repeat":        "d 7",
+		"auto_complete": true,
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	task, _ := parentTask(t, parent)
+	assert.Equal(t, true, task["auto_complete"])
+	next, err := time.Parse(`20060102`, fmt.Sprint(task["date"]))
+	assert.NoError(t, err)
+
+	for _, id := range items[1:] {
+		ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+
+	task, progress = parentTask(t, parent)
+	assert.Equal(t, next.AddDate(0, 0, 7).Format(`20060102`), task["date"])
+	assert.EqualValues(t, 0, progress["done"])
+	assert.EqualValues(t, 3, progress["total"])
+
+	// subtasks follow their parent into another project and can't leave it alone
+	ret, err = postJSON("api/project", map[string]any{"name": fmt.Sprintf("Поездка %d", time.Now().UnixNano())}, http.MethodPost)
+	assert.NoError(t, err)
+	project := fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":         items[0],
+		"date":       date,
+		"title":      "Паспорт",
+		"project_id": project,
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":         parent,
+		"date":       fmt.Sprint(task["date"]),
+		"title":      "Собрать вещи",
+		"project_id": project,
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	for _, id := range append(items, parent) {
+		task, _ := parentTask(t, id)
+		assert.Equal(t, project, fmt.Sprint(task["project_id"]))
+	}
+
+	for _, id := range append(items, parent) {
+		ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+
+	ret, err = postJSON("api/project?id="+project, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tagsResponse struct {
+	Tags []struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	} `json:"tags"`
+}
+
+func tagCount(t *testing.T, name string) int {
+	body, err := requestJSON("api/tags", nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var resp tagsResponse
+	err = json.Unmarshal(body, &resp)
+	assert.NoError(t, err)
+
+	for _, tag := range resp.Tags {
+		if tag.Name == name {
+			return tag.Count
+		}
+	}
+	return 0
+}
+
+func tasksWithTags(t *testing.T, query string) []map[string]any {
+	body, err := requestJSON("api/tasks?"+query, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]map[string]any
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+	return m["tasks"]
+}
+
+func TestTags(t *testing.T) {
+	ret, err := postJSON("api/task", map[string]any{
+		"title": "Подготовить презентацию",
+		"tags":  []string{"Work", "#slides", "work"},
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := ret["id"].(string)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"title": "Сходить в спортзал",
+		"tags":  []string{"health"},
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	other := ret["id"].(string)
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var task map[string]any
+	err = json.Unmarshal(body, &task)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"slides", "work"}, task["tags"])
+
+	assert.Equal(t, 1, tagCount(t, "work"))
+
+	tasks := tasksWithTags(t, "tag=work")
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, id, tasks[0]["id"])
+	tasks = tasksWithTags(t, "search=%23health")
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, other, tasks[0]["id"])
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":    id,
+		"title": "Подготовить презентацию",
+		"tags":  []string{},
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Equal(t, 0, tagCount(t, "work"))
+	assert.Empty(t, tasksWithTags(t, "tag=work"))
+
+	ret, err = postJSON("api/task", map[string]any{
+		"title": "Плохой тег",
+		"tags":  []string{"два слова"},
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	for _, v := range []string{id, other} {
+		ret, err = postJSON("api/task?id="+v, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+	assert.Equal(t, 0, tagCount(t, "health"))
+}
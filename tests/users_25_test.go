@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// requestAs sends a request with the token of another user.
+func requestAs(t *testing.T, token, apipath string, values map[string]any, method string) (int, map[string]any) {
+	var data []byte
+
+	if len(values) > 0 {
+		var err error
+		data, err = json.Marshal(values)
+		assert.NoError(t, err)
+	}
+
+	req, err := http.NewRequest(method, getURL(apipath), bytes.NewBuffer(data))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "token", Value: token})
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var m map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&m))
+	return resp.StatusCode, m
+}
+
+func TestUsers(t *testing.T) {
+	if len(Token) == 0 {
+		t.Skip("authentication is off")
+	}
+
+	name := fmt.Sprintf("alice%d", time.Now().UnixNano())
+
+	ret, err := postJSON("api/user", map[string]any{
+		"name":     name,
+		"password": "secret",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	userID := fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/user", map[string]any{
+		"name":     name,
+		"password": "other",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/signin", map[string]any{
+		"name":     name,
+		"password": "wrong",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["token"])
+
+	ret, err = postJSON("api/signin", map[string]any{
+		"name":     name,
+		"password": "secret",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	token := fmt.Sprint(ret["token"])
+	assert.NotEmpty(t, token)
+
+	mine := addTask(t, task{
+		title: "Задача администратора",
+	})
+
+	status, ret := requestAs(t, token, "api/task", map[string]any{
+		"date":  time.Now().Format(`20060102`),
+		"title": "Личная задача",
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusOK, status)
+	theirs := fmt.Sprint(ret["id"])
+
+	// each user only sees their own tasks
+	assert.NotContains(t, taskIDs(t, "search=Личная"), theirs)
+
+	status, ret = requestAs(t, token, "api/tasks?search=Личная", nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, ret["tasks"], 1)
+
+	status, ret = requestAs(t, token, "api/task?id="+mine, nil, http.MethodGet)
+	assert.NotEmpty(t, ret["error"])
+
+	status, ret = requestAs(t, token, "api/task?id="+mine, nil, http.MethodDelete)
+	assert.NotEmpty(t, ret["error"])
+	assert.NotEmpty(t, getTask(t, mine)["title"])
+
+	status, ret = requestAs(t, token, "api/projects", nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, ret["projects"], 1)
+
+	// only admins manage users
+	status, _ = requestAs(t, token, "api/users", nil, http.MethodGet)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	body, err := requestJSON("api/users", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), name)
+	assert.NotContains(t, string(body), "password")
+
+	ret, err = postJSON("api/user?id=1", nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	// a new password ends existing sessions
+	ret, err = postJSON("api/user", map[string]any{
+		"id":       userID,
+		"password": "changed",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	status, _ = requestAs(t, token, "api/tasks", nil, http.MethodGet)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	ret, err = postJSON("api/user?id="+userID, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	ret, err = postJSON("api/signin", map[string]any{
+		"name":     name,
+		"password": "changed",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["token"])
+
+	ret, err = postJSON("api/task?id="+mine, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// requestIfMatch sends a request with an If-Match header and returns the
+// status, the ETag and the decoded body.
+func requestIfMatch(t *testing.T, method, apipath, ifMatch string, values map[string]any) (int, string, map[string]any) {
+	var data []byte
+
+	if values != nil {
+		var err error
+		data, err = json.Marshal(values)
+		assert.NoError(t, err)
+	}
+
+	req, err := http.NewRequest(method, getURL(apipath), bytes.NewBuffer(data))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+
+	resp, err := sendWithToken(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var m map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&m))
+	return resp.StatusCode, resp.Header.Get("ETag"), m
+}
+
+func TestVersions(t *testing.T) {
+	id := addTask(t, task{
+		title: "Отчёт за квартал",
+	})
+
+	status, tag, _ := requestIfMatch(t, http.MethodGet, "api/task?id="+id, "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `"1"`, tag)
+
+	update := map[string]any{
+		"id":    id,
+		"date":  time.Now().Format(`20060102`),
+		"title": "Отчёт за квартал",
+	}
+
+	status, _, _ = requestIfMatch(t, http.MethodPut, "api/task", `"1"`, update)
+	assert.Equal(t, http.StatusOK, status)
+
+	_, tag, _ = requestIfMatch(t, http.MethodGet, "api/task?id="+id, "", nil)
+	assert.Equal(t, `"2"`, tag)
+
+	// the other tab still holds version 1
+	update["title"] = "Отчёт за год"
+	status, tag, ret := requestIfMatch(t, http.MethodPut, "api/task", `"1"`, update)
+	assert.Equal(t, http.StatusPreconditionFailed, status)
+	assert.Equal(t, `"2"`, tag)
+	assert.EqualValues(t, 2, ret["version"])
+	assert.NotEmpty(t, ret["error"])
+	assert.Equal(t, "Отчёт за квартал", getTask(t, id)["title"])
+
+	status, _, ret = requestIfMatch(t, http.MethodPut, "api/task", "version", update)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.NotEmpty(t, ret["error"])
+
+	status, _, _ = requestIfMatch(t, http.MethodPut, "api/task", "", update)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, tag, _ = requestIfMatch(t, http.MethodPost, "api/task/done?id="+id, `"2"`, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, status)
+	assert.Equal(t, `"3"`, tag)
+
+	status, _, _ = requestIfMatch(t, http.MethodPost, "api/task/done?id="+id, `W/"3"`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, completedTask(t, id, "")["completed_at"])
+}